@@ -0,0 +1,255 @@
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// dockerHubRegistry is the registry host used for images without explicit registry
+const dockerHubRegistry = "docker.io"
+
+// dockerHubServerURL is the server address under which Docker Hub credentials are stored by the docker CLI
+const dockerHubServerURL = "https://index.docker.io/v1/"
+
+// defaultCredentialHelperTimeout is the maximum time a credential helper may take, when the context has no earlier deadline
+const defaultCredentialHelperTimeout = 30 * time.Second
+
+// CredentialsProvider is a source of registry credentials
+// Credentials returns an empty configuration when it has no credentials for the registry, meaning anonymous access
+// The lookup must be aborted when the context is done
+type CredentialsProvider interface {
+	Credentials(ctx context.Context, registry string) (docker.AuthConfiguration, error)
+}
+
+// StaticCredentials holds explicit credentials, indexed by registry host (ex : registry.example.com:5000, docker.io)
+type StaticCredentials map[string]docker.AuthConfiguration
+
+// Credentials returns the credentials registered for the registry
+// Keys are compared once normalized, so that URLs like https://index.docker.io/v1/ can be used
+func (s StaticCredentials) Credentials(ctx context.Context, registry string) (docker.AuthConfiguration, error) {
+	registry = normalizeRegistry(registry)
+	for key, auth := range s {
+		if normalizeRegistry(key) == registry {
+			return auth, nil
+		}
+	}
+	return docker.AuthConfiguration{}, nil
+}
+
+// ChainCredentials asks each provider in turn and returns the first credentials found
+type ChainCredentials []CredentialsProvider
+
+// Credentials returns the credentials of the first provider knowing the registry
+func (chain ChainCredentials) Credentials(ctx context.Context, registry string) (docker.AuthConfiguration, error) {
+	for _, provider := range chain {
+		auth, err := provider.Credentials(ctx, registry)
+		if err != nil {
+			return docker.AuthConfiguration{}, err
+		}
+		if auth != (docker.AuthConfiguration{}) {
+			return auth, nil
+		}
+	}
+	return docker.AuthConfiguration{}, nil
+}
+
+// DockerConfigCredentials reads credentials from a docker CLI configuration file (~/.docker/config.json)
+// Base64 encoded "auths" entries, "credsHelpers" and "credsStore" helper binaries are supported
+// The file is read on each call, so that a new "docker login" is taken into account
+type DockerConfigCredentials struct {
+	Path string // Path of the configuration file
+}
+
+// NewDockerConfigCredentials creates a provider reading the default docker CLI configuration file
+// $DOCKER_CONFIG/config.json is used when the variable is set, ~/.docker/config.json otherwise
+func NewDockerConfigCredentials() (*DockerConfigCredentials, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("Can't find docker configuration directory : %v", err)
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	return &DockerConfigCredentials{Path: filepath.Join(dir, "config.json")}, nil
+}
+
+// dockerConfigFile is the subset of the docker CLI configuration file used for authentication
+type dockerConfigFile struct {
+	Auths        map[string]dockerConfigAuth `json:"auths"`
+	CredsStore   string                      `json:"credsStore"`
+	CredsHelpers map[string]string           `json:"credsHelpers"`
+}
+
+// dockerConfigAuth is an entry of the "auths" section of the docker CLI configuration file
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	Email         string `json:"email"`
+	IdentityToken string `json:"identitytoken"`
+	RegistryToken string `json:"registrytoken"`
+}
+
+// Credentials returns the credentials stored for the registry
+// A missing configuration file means no credentials
+func (d *DockerConfigCredentials) Credentials(ctx context.Context, registry string) (docker.AuthConfiguration, error) {
+	content, err := os.ReadFile(d.Path)
+	if os.IsNotExist(err) {
+		return docker.AuthConfiguration{}, nil
+	}
+	if err != nil {
+		return docker.AuthConfiguration{}, fmt.Errorf("Can't read docker configuration %v : %v", d.Path, err)
+	}
+	var config dockerConfigFile
+	if err := json.Unmarshal(content, &config); err != nil {
+		return docker.AuthConfiguration{}, fmt.Errorf("Can't decode docker configuration %v : %v", d.Path, err)
+	}
+	return config.credentials(ctx, normalizeRegistry(registry))
+}
+
+// credentials resolves the credentials of a normalized registry host
+// Helpers take precedence over "auths" entries, as the docker CLI does
+func (config dockerConfigFile) credentials(ctx context.Context, registry string) (docker.AuthConfiguration, error) {
+	for key, helper := range config.CredsHelpers {
+		if normalizeRegistry(key) == registry {
+			return helperCredentials(ctx, helper, registry)
+		}
+	}
+	if config.CredsStore != "" {
+		return helperCredentials(ctx, config.CredsStore, registry)
+	}
+	for key, entry := range config.Auths {
+		if normalizeRegistry(key) == registry {
+			return entry.toAuthConfiguration(registry)
+		}
+	}
+	return docker.AuthConfiguration{}, nil
+}
+
+// toAuthConfiguration decodes an "auths" entry
+func (entry dockerConfigAuth) toAuthConfiguration(registry string) (docker.AuthConfiguration, error) {
+	auth := docker.AuthConfiguration{
+		Username:      entry.Username,
+		Password:      entry.Password,
+		Email:         entry.Email,
+		ServerAddress: serverURL(registry),
+		IdentityToken: entry.IdentityToken,
+		RegistryToken: entry.RegistryToken,
+	}
+	if entry.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return docker.AuthConfiguration{}, fmt.Errorf("Can't decode credentials of registry %v : %v", registry, err)
+		}
+		userPass := strings.SplitN(string(decoded), ":", 2)
+		if len(userPass) != 2 {
+			return docker.AuthConfiguration{}, fmt.Errorf("Invalid credentials of registry %v : expected username:password", registry)
+		}
+		auth.Username = userPass[0]
+		auth.Password = userPass[1]
+	}
+	return auth, nil
+}
+
+// helperCredentials gets credentials from a docker-credential-<helper> binary, following the docker credential helpers protocol
+// The helper is killed when the context is done, or after 30 seconds, so that a helper waiting for a prompt does not block pulls
+func helperCredentials(ctx context.Context, helper, registry string) (docker.AuthConfiguration, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultCredentialHelperTimeout)
+	defer cancel()
+	cmd := osexec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(serverURL(registry))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		output := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(output, "credentials not found") {
+			return docker.AuthConfiguration{}, nil
+		}
+		var exitErr *osexec.ExitError
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		} else if errors.As(err, &exitErr) && output != "" {
+			err = errors.New(output)
+		}
+		return docker.AuthConfiguration{}, fmt.Errorf("Can't get credentials of registry %v from helper %v : %w", registry, helper, err)
+	}
+
+	var res struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &res); err != nil {
+		return docker.AuthConfiguration{}, fmt.Errorf("Can't decode credentials of registry %v from helper %v : %v", registry, helper, err)
+	}
+	auth := docker.AuthConfiguration{ServerAddress: serverURL(registry)}
+	if res.Username == "<token>" {
+		// Helpers store identity tokens with this special username
+		auth.IdentityToken = res.Secret
+	} else {
+		auth.Username = res.Username
+		auth.Password = res.Secret
+	}
+	return auth, nil
+}
+
+// registryHost returns the registry host of an image reference (ex : registry.example.com:5000/app:1.0)
+// Images without explicit registry come from Docker Hub
+func registryHost(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return dockerHubRegistry
+	}
+	host := parts[0]
+	if host != "localhost" && !strings.ContainsAny(host, ".:") {
+		// First component is a Docker Hub namespace (ex : library/redis)
+		return dockerHubRegistry
+	}
+	return normalizeRegistry(host)
+}
+
+// normalizeRegistry turns a registry address, possibly an URL, into its host
+// All Docker Hub aliases are normalized to docker.io
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i != -1 {
+		registry = registry[:i]
+	}
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+	return registry
+}
+
+// serverURL returns the address under which credentials of a registry host are stored
+func serverURL(registry string) string {
+	if registry == dockerHubRegistry {
+		return dockerHubServerURL
+	}
+	return registry
+}
+
+// registryAuth returns the credentials to use to pull the image
+// Explicit credentials of the client are used first, then its credentials provider
+// The lookup is aborted when the context is done
+func (c *Client) registryAuth(ctx context.Context, image string) (docker.AuthConfiguration, error) {
+	providers := ChainCredentials{c.RegistryAuths}
+	if c.Credentials != nil {
+		providers = append(providers, c.Credentials)
+	}
+	return providers.Credentials(ctx, registryHost(image))
+}
@@ -0,0 +1,154 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryHost(t *testing.T) {
+	assert.Equal(t, "docker.io", registryHost("redis"))
+	assert.Equal(t, "docker.io", registryHost("redis:latest"))
+	assert.Equal(t, "docker.io", registryHost("library/redis:latest"))
+	assert.Equal(t, "docker.io", registryHost("index.docker.io/library/redis"))
+	assert.Equal(t, "registry.example.com", registryHost("registry.example.com/team/app:1.0"))
+	assert.Equal(t, "registry.example.com:5000", registryHost("registry.example.com:5000/app"))
+	assert.Equal(t, "localhost", registryHost("localhost/app"))
+	assert.Equal(t, "localhost:5000", registryHost("localhost:5000/app@sha256:abc"))
+}
+
+func TestStaticCredentials(t *testing.T) {
+	creds := StaticCredentials{
+		"https://index.docker.io/v1/": {Username: "hub"},
+		"registry.example.com":        {Username: "private"},
+	}
+	auth, err := creds.Credentials(context.Background(), "docker.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "hub", auth.Username)
+	auth, _ = creds.Credentials(context.Background(), "registry.example.com")
+	assert.Equal(t, "private", auth.Username)
+	auth, _ = creds.Credentials(context.Background(), "other.example.com")
+	assert.Equal(t, docker.AuthConfiguration{}, auth)
+}
+
+func TestDockerConfigCredentials(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	// "dXNlcjpwYXNzOndvcmQ=" is base64 for "user:pass:word"
+	config := `{"auths": {
+		"https://index.docker.io/v1/": {"auth": "dXNlcjpwYXNzOndvcmQ="},
+		"registry.example.com": {"username": "plain", "password": "secret"}
+	}}`
+	assert.NoError(t, os.WriteFile(path, []byte(config), 0600))
+
+	creds := &DockerConfigCredentials{Path: path}
+	auth, err := creds.Credentials(context.Background(), "docker.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "user", auth.Username)
+	assert.Equal(t, "pass:word", auth.Password)
+	assert.Equal(t, "https://index.docker.io/v1/", auth.ServerAddress)
+
+	auth, err = creds.Credentials(context.Background(), "registry.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", auth.Username)
+	assert.Equal(t, "secret", auth.Password)
+
+	auth, err = creds.Credentials(context.Background(), "unknown.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, docker.AuthConfiguration{}, auth)
+
+	missing := &DockerConfigCredentials{Path: filepath.Join(dir, "missing.json")}
+	auth, err = missing.Credentials(context.Background(), "docker.io")
+	assert.NoError(t, err)
+	assert.Equal(t, docker.AuthConfiguration{}, auth)
+}
+
+func TestDockerConfigCredentialsHelpers(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake credential helper is a shell script")
+	}
+	dir := t.TempDir()
+	helper := `#!/bin/sh
+read server
+case "$server" in
+  registry.example.com) echo '{"ServerURL":"registry.example.com","Username":"helped","Secret":"s3cret"}' ;;
+  https://index.docker.io/v1/) echo '{"ServerURL":"https://index.docker.io/v1/","Username":"<token>","Secret":"t0ken"}' ;;
+  *) echo "credentials not found in native keychain"; exit 1 ;;
+esac
+`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(helper), 0700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(dir, "config.json")
+	config := `{"credsHelpers": {"registry.example.com": "fake"}, "credsStore": "fake"}`
+	assert.NoError(t, os.WriteFile(path, []byte(config), 0600))
+	creds := &DockerConfigCredentials{Path: path}
+
+	auth, err := creds.Credentials(context.Background(), "registry.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "helped", auth.Username)
+	assert.Equal(t, "s3cret", auth.Password)
+
+	auth, err = creds.Credentials(context.Background(), "docker.io")
+	assert.NoError(t, err)
+	assert.Equal(t, "t0ken", auth.IdentityToken)
+	assert.Empty(t, auth.Username)
+
+	auth, err = creds.Credentials(context.Background(), "unknown.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, docker.AuthConfiguration{}, auth)
+}
+
+func TestClientRegistryAuth(t *testing.T) {
+	client := &Client{
+		RegistryAuths: StaticCredentials{"registry.example.com": {Username: "explicit"}},
+		Credentials:   StaticCredentials{"registry.example.com": {Username: "fallback"}, "docker.io": {Username: "hub"}},
+	}
+	auth, err := client.registryAuth(context.Background(), "registry.example.com/app:1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "explicit", auth.Username)
+	auth, err = client.registryAuth(context.Background(), "redis")
+	assert.NoError(t, err)
+	assert.Equal(t, "hub", auth.Username)
+	auth, err = (&Client{}).registryAuth(context.Background(), "redis")
+	assert.NoError(t, err)
+	assert.Equal(t, docker.AuthConfiguration{}, auth)
+}
+
+func TestHelperCredentialsCanceled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake credential helper is a shell script")
+	}
+	dir := t.TempDir()
+	helper := "#!/bin/sh\nexec sleep 60\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "docker-credential-stuck"), []byte(helper), 0700))
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := helperCredentials(ctx, "stuck", "registry.example.com")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestNewClientReadsDockerConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	config := `{"auths": {"registry.example.com": {"username": "plain", "password": "secret"}}}`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600))
+
+	client, err := NewClient("tcp://localhost:2375")
+	assert.NoError(t, err)
+	assert.Equal(t, &DockerConfigCredentials{Path: filepath.Join(dir, "config.json")}, client.Credentials)
+	auth, err := client.registryAuth(context.Background(), "registry.example.com/app:1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain", auth.Username)
+}
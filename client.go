@@ -1,10 +1,21 @@
 package dockerapi
 
-import "github.com/fsouza/go-dockerclient"
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fsouza/go-dockerclient"
+)
 
 // Client is the docker client for this API
 type Client struct {
-	Docker *docker.Client
+	Docker        *docker.Client
+	RegistryAuths StaticCredentials   // Explicit credentials per registry host, used first when pulling images
+	Credentials   CredentialsProvider // Source of other registry credentials. The docker CLI configuration with NewClient*, anonymous pulls if nil
 }
 
 // TLSClientFromBytesParameters is a struct containing the TLS configuration
@@ -20,7 +31,7 @@ func NewClient(endpoint string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{c}, nil
+	return newClient(c), nil
 }
 
 // NewTLSClient create a client for a TLS secured Docker engine
@@ -30,7 +41,7 @@ func NewTLSClient(host, certPEM, keyPEM, caPEM string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Client{c}, nil
+	return newClient(c), nil
 }
 
 // NewTLSClientFromBytes create a client for a TLS secured Docker engine
@@ -41,5 +52,60 @@ func NewTLSClientFromBytes(params TLSClientFromBytesParameters) (*Client, error)
 		return nil, err
 	}
 	c.TLSConfig.InsecureSkipVerify = params.InsecureSkipVerify
-	return &Client{c}, nil
+	return newClient(c), nil
+}
+
+// newClient wraps a fsouza client, with the credentials of the docker CLI configuration
+// Pulls are anonymous when the configuration directory can't be found
+func newClient(c *docker.Client) *Client {
+	client := &Client{Docker: c}
+	if creds, err := NewDockerConfigCredentials(); err == nil {
+		client.Credentials = creds
+	}
+	return client
+}
+
+// request sends a request to the engine API, for parameters the fsouza client doesn't support
+// The HTTP client of the fsouza client is used, so that sockets and TLS are handled the same way
+// Returns a *docker.Error if the engine answers with an error status. The caller must close the response body
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	endpoint, err := url.Parse(c.Docker.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+	u := url.URL{Scheme: "http", Host: endpoint.Host, Path: path, RawQuery: query.Encode()}
+	switch endpoint.Scheme {
+	case "unix", "npipe":
+		// The transport dials the socket whatever the host
+		u.Host = "docker"
+	default:
+		if c.Docker.TLSConfig != nil {
+			u.Scheme = "https"
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.Docker.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(content))
+		if json.Unmarshal(content, &apiErr) == nil && apiErr.Message != "" {
+			message = apiErr.Message
+		}
+		return nil, &docker.Error{Status: resp.StatusCode, Message: message}
+	}
+	return resp, nil
 }
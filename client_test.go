@@ -0,0 +1,56 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRequest(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Write([]byte(r.Method + " " + r.URL.RawQuery + " " + r.Header.Get("Content-Type") + " " + string(body)))
+		case "/json-error":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"No such container: web"}`))
+		case "/text-error":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		}
+	})
+
+	resp, err := client.request(context.Background(), http.MethodPost, "/echo", url.Values{"t": {"10"}}, strings.NewReader(`{}`))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "POST t=10 application/json {}", string(body))
+
+	_, err = client.request(context.Background(), http.MethodGet, "/json-error", nil, nil)
+	var apiErr *docker.Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, &docker.Error{Status: http.StatusNotFound, Message: "No such container: web"}, apiErr)
+
+	_, err = client.request(context.Background(), http.MethodGet, "/text-error", nil, nil)
+	assert.Equal(t, &docker.Error{Status: http.StatusInternalServerError, Message: "boom"}, err)
+
+	// Not modified is not an error of the engine, callers decide what it means
+	resp, err = client.request(context.Background(), http.MethodPost, "/not-modified", nil, nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.request(ctx, http.MethodGet, "/echo", nil, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -1,13 +1,15 @@
 package dockerapi
 
 import (
-	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/soprasteria/dockerapi/utils"
 
@@ -33,8 +35,10 @@ type SimpleContainers interface {
 
 // Container is a docker container
 type Container struct {
-	Container *docker.Container // fsouza docker client. To use if this wrapper is not able to do what you want
-	Client    *Client           // wrapper client used to create the container. Will be used for any other Docker action
+	Container *docker.Container   // fsouza docker client. To use if this wrapper is not able to do what you want
+	Client    *Client             // wrapper client used to create the container. Will be used for any other Docker action
+	DependsOn []string            // Names of the containers this container depends on, in addition to its links. Used to order operations on pools
+	Networks  []NetworkAttachment // Networks the container is attached to at creation
 }
 
 // PortBinding binds the port from host and container from host
@@ -51,6 +55,13 @@ type Parameters struct {
 	MemorySwap int64
 	CPUShares  int64
 	CPUSet     string
+
+	CPUQuota       int64 // Microseconds of CPU per period. Format : at least 1000
+	CPUPeriod      int64 // Length of a CPU period in microseconds, 100000 if 0. Format : between 1000 and 1000000
+	NanoCPUs       int64 // Number of CPUs in billionths (ex : 1500000000 for 1.5 CPU). Can't be used with CPUQuota/CPUPeriod
+	PidsLimit      int64 // Maximum number of processes, unlimited if 0 or -1
+	ShmSize        int64 // Size of /dev/shm in bytes, 64MB if 0
+	OomKillDisable bool  // Disables the OOM killer for the container
 }
 
 // ContainerOptions defines options for container initialisation
@@ -59,7 +70,8 @@ type ContainerOptions struct {
 	Name         string            // Name of the container
 	PortBindings []PortBinding     // List of ports to bind
 	Cmd          []string          // command to launch when starting the container
-	Binds        []string          // Volume bindings. Format :  externalpath:internalpath:r(w|o)
+	Binds        []string          // Volume bindings. Format :  externalpath:internalpath:r(w|o). Mounts allow more options
+	Mounts       []Mount           // Binds, volumes and tmpfs mounted inside the container
 	Links        []string          // Links to use inside the container. Format : externalname:internalname
 	Env          []string          // Environment variables to set for the container. Format : key=value
 	Hostname     string            // Hostname of the docker container
@@ -67,6 +79,32 @@ type ContainerOptions struct {
 	Parameters   Parameters        // Parameters list all docker parameters
 	Labels       map[string]string // Labels inside the container
 	NetworkMode  string            // NetworkMode which used to start the docker container
+	DependsOn    []string          // Names of the containers to start before this one in a pool, in addition to the links
+	Healthcheck  *Healthcheck      // Healthcheck of the container. Healthcheck of the image if nil
+
+	RestartPolicy  RestartPolicy     // Restart policy of the container. Never restarted if empty
+	CapAdd         []string          // Kernel capabilities to add (ex : NET_ADMIN, ALL)
+	CapDrop        []string          // Kernel capabilities to drop (ex : MKNOD, ALL)
+	Privileged     bool              // Gives all capabilities and access to all devices
+	ReadonlyRootfs bool              // Mounts the root filesystem of the container as read only
+	SecurityOpt    []string          // Security options. Format : key=value (ex : seccomp=unconfined) or no-new-privileges
+	Ulimits        []Ulimit          // Resource limits of the processes of the container
+	Tmpfs          map[string]string // Tmpfs mounts, by path inside the container. Format of the options : size=64m,mode=1777
+	Devices        []string          // Devices of the host to add. Format : hostpath[:containerpath[:permissions]], permissions being rwm by default
+	DNS            []string          // IPs of the DNS servers. DNS of the host if empty
+	DNSSearch      []string          // DNS search domains
+	LogConfig      LogConfig         // Logging driver of the container. Default driver of the engine if empty
+
+	Entrypoint  []string      // Entrypoint of the container. Entrypoint of the image if nil, none if [""]
+	User        string        // User running the processes of the container. Format : user, user:group, uid or uid:gid
+	WorkingDir  string        // Working directory of the processes of the container. Must be absolute
+	StopSignal  string        // Signal stopping the container (ex : SIGINT). SIGTERM if empty
+	StopTimeout time.Duration // Time to wait before killing a stopping container, with a precision of one second. Engine default (10 seconds) if 0
+	Tty         bool          // Allocates a pseudo-TTY
+	OpenStdin   bool          // Keeps stdin open
+	Domainname  string        // Domain name of the container
+
+	Networks []NetworkAttachment // Networks to attach the container to at creation. The first one is the network mode
 }
 
 // NewContainer initializes a new container, ready to be created
@@ -95,10 +133,30 @@ func (c *Client) NewContainer(o ContainerOptions) (*Container, error) {
 		portBindings[port] = []docker.PortBinding{{HostIP: hostIP, HostPort: binding.HostPort}}
 	}
 
+	healthcheck, err := o.Healthcheck.healthConfig()
+	if err != nil {
+		return nil, err
+	}
+	if o.WorkingDir != "" && !strings.HasPrefix(o.WorkingDir, "/") {
+		return nil, fmt.Errorf("Working directory %q must be absolute", o.WorkingDir)
+	}
+	if err := validateAttachments(o.Networks); err != nil {
+		return nil, err
+	}
+	if len(o.Networks) > 0 {
+		if o.NetworkMode != "" && o.NetworkMode != o.Networks[0].Network {
+			return nil, fmt.Errorf("Network mode %v can't be used with network attachments", o.NetworkMode)
+		}
+		o.NetworkMode = o.Networks[0].Network
+	}
+	if o.StopTimeout < 0 || (o.StopTimeout > 0 && o.StopTimeout%time.Second != 0) {
+		return nil, fmt.Errorf("Stop timeout must be a positive number of seconds, got %v", o.StopTimeout)
+	}
+
 	// Handle volume bindings and default behaviour
 	volumeBindings := []string{}
 	for _, binding := range o.Binds {
-		volume := strings.Split(binding, ":")
+		volume := splitBind(binding)
 		if len(volume) == 2 {
 			// external:internal -> external:internal:rw
 			binding = binding + ":rw"
@@ -116,6 +174,15 @@ func (c *Client) NewContainer(o ContainerOptions) (*Container, error) {
 			Hostname:     o.Hostname,
 			ExposedPorts: exposedPorts,
 			Labels:       o.Labels,
+			Healthcheck:  healthcheck,
+			Entrypoint:   o.Entrypoint,
+			User:         o.User,
+			WorkingDir:   o.WorkingDir,
+			StopSignal:   o.StopSignal,
+			StopTimeout:  int(o.StopTimeout / time.Second),
+			Tty:          o.Tty,
+			OpenStdin:    o.OpenStdin,
+			Domainname:   o.Domainname,
 		},
 		HostConfig: &docker.HostConfig{
 			PortBindings: portBindings,
@@ -129,16 +196,33 @@ func (c *Client) NewContainer(o ContainerOptions) (*Container, error) {
 			NetworkMode:  o.NetworkMode,
 		},
 	}
+	if err := o.applyHostConfig(container.HostConfig); err != nil {
+		return nil, err
+	}
+	if container.HostConfig.Mounts, err = o.hostMounts(); err != nil {
+		return nil, err
+	}
 
 	return &Container{
 		Container: container,
 		Client:    c,
+		DependsOn: o.DependsOn,
+		Networks:  o.Networks,
 	}, nil
 }
 
 // InspectContainer inspects the container on server from an id
 func (c *Client) InspectContainer(id string) (*Container, error) {
-	cont, err := c.Docker.InspectContainer(id)
+	return c.InspectContainerWithContext(context.Background(), id)
+}
+
+// InspectContainerWithContext inspects the container on server from an id
+// The inspection is aborted when the context is done
+func (c *Client) InspectContainerWithContext(ctx context.Context, id string) (*Container, error) {
+	cont, err := c.Docker.InspectContainerWithOptions(docker.InspectContainerOptions{
+		ID:      id,
+		Context: ctx,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -150,12 +234,24 @@ func (c *Client) InspectContainer(id string) (*Container, error) {
 
 // ListRunningContainers list all running containers on docker engine
 func (c *Client) ListRunningContainers() (SimpleContainers, error) {
-	return c.listContainers(docker.ListContainersOptions{})
+	return c.ListRunningContainersWithContext(context.Background())
+}
+
+// ListRunningContainersWithContext list all running containers on docker engine
+// The listing is aborted when the context is done
+func (c *Client) ListRunningContainersWithContext(ctx context.Context) (SimpleContainers, error) {
+	return c.listContainers(docker.ListContainersOptions{Context: ctx})
 }
 
 // ListContainers list all running and non-running containers on docker engine
 func (c *Client) ListContainers() (SimpleContainers, error) {
-	return c.listContainers(docker.ListContainersOptions{All: true})
+	return c.ListContainersWithContext(context.Background())
+}
+
+// ListContainersWithContext list all running and non-running containers on docker engine
+// The listing is aborted when the context is done
+func (c *Client) ListContainersWithContext(ctx context.Context) (SimpleContainers, error) {
+	return c.listContainers(docker.ListContainersOptions{All: true, Context: ctx})
 }
 
 func (c *Client) listContainers(options docker.ListContainersOptions) (SimpleContainers, error) {
@@ -295,6 +391,12 @@ func (c *Container) GetEnvs() []string {
 
 // Rename renames a container's name to another
 func (c *Container) Rename(newName string) error {
+	return c.RenameWithContext(context.Background(), newName)
+}
+
+// RenameWithContext renames a container's name to another
+// The renaming is aborted when the context is done
+func (c *Container) RenameWithContext(ctx context.Context, newName string) error {
 	if newName == "" {
 		return errors.New("New name is empty")
 	}
@@ -303,8 +405,9 @@ func (c *Container) Rename(newName string) error {
 	}
 
 	options := docker.RenameContainerOptions{
-		ID:   c.ID(),
-		Name: newName,
+		ID:      c.ID(),
+		Name:    newName,
+		Context: ctx,
 	}
 	err := c.Client.Docker.RenameContainer(options)
 
@@ -312,7 +415,7 @@ func (c *Container) Rename(newName string) error {
 		return fmt.Errorf("Can't rename %v to %v because %v", c.Name(), newName, err.Error())
 	}
 
-	return c.Refresh()
+	return c.RefreshWithContext(ctx)
 }
 
 // Clone clones an existing container configuration
@@ -336,13 +439,21 @@ func (c *Container) Clone() (*Container, error) {
 	return &Container{
 		Container: &clone,
 		Client:    c.Client,
+		DependsOn: append([]string{}, c.DependsOn...),
+		Networks:  append([]NetworkAttachment{}, c.Networks...),
 	}, nil
 
 }
 
 // Refresh refresh container from the server
 func (c *Container) Refresh() error {
-	cont, err := c.Client.InspectContainer(c.Container.ID)
+	return c.RefreshWithContext(context.Background())
+}
+
+// RefreshWithContext refresh container from the server
+// The refresh is aborted when the context is done
+func (c *Container) RefreshWithContext(ctx context.Context) error {
+	cont, err := c.Client.InspectContainerWithContext(ctx, c.Container.ID)
 	if err != nil {
 		return err
 	}
@@ -352,20 +463,61 @@ func (c *Container) Refresh() error {
 
 // Create creates the container
 func (c *Container) Create() error {
+	return c.CreateWithContext(context.Background())
+}
+
+// CreateWithContext creates the container
+// The container is attached to the first of its networks at creation, then connected to the others
+// If a network can't be connected, the container is removed. The error also holds the removal failure, if any
+// The creation is aborted when the context is done
+func (c *Container) CreateWithContext(ctx context.Context) error {
+	var networkConfig *docker.NetworkingConfig
+	if len(c.Networks) > 0 {
+		first := c.Networks[0]
+		endpoint, err := first.endpointConfig()
+		if err != nil {
+			return err
+		}
+		networkConfig = &docker.NetworkingConfig{EndpointsConfig: map[string]*docker.EndpointConfig{first.Network: endpoint}}
+	}
+
+	config := c.Container
 	cont, err := c.Client.Docker.CreateContainer(docker.CreateContainerOptions{
-		Name:       c.Container.Name,
-		Config:     c.Container.Config,
-		HostConfig: c.Container.HostConfig,
+		Name:             c.Container.Name,
+		Config:           c.Container.Config,
+		HostConfig:       c.Container.HostConfig,
+		NetworkingConfig: networkConfig,
+		Context:          ctx,
 	})
 	if err != nil {
 		return err
 	}
 	c.Container = cont
-	return err
+
+	for i := 1; i < len(c.Networks); i++ {
+		attachment := c.Networks[i]
+		err = c.ConnectNetworkWithContext(ctx, attachment.Network, attachment.EndpointOptions)
+		if err == nil {
+			continue
+		}
+		// The container must not be left half connected, even when the context is done
+		if errr := c.RemoveWithContext(context.Background(), true); errr != nil {
+			err = fmt.Errorf("%w, and the container can't be removed : %w", err, errr)
+		}
+		c.Container = config
+		return err
+	}
+	return nil
 }
 
 // CreateWithAliases creates the container with network aliases
 func (c *Container) CreateWithAliases(aliases []string) error {
+	return c.CreateWithAliasesWithContext(context.Background(), aliases)
+}
+
+// CreateWithAliasesWithContext creates the container with network aliases
+// The creation is aborted when the context is done
+func (c *Container) CreateWithAliasesWithContext(ctx context.Context, aliases []string) error {
 	network := c.Container.HostConfig.NetworkMode
 	if utils.ContainsString([]string{"host", "bridge", "none"}, network) {
 		return errors.New("Creating container with aliases is not allowed on networks 'bridge', 'host' or 'none'")
@@ -380,6 +532,7 @@ func (c *Container) CreateWithAliases(aliases []string) error {
 		Config:           c.Container.Config,
 		HostConfig:       c.Container.HostConfig,
 		NetworkingConfig: &networkConfig,
+		Context:          ctx,
 	})
 	if err != nil {
 		return err
@@ -390,63 +543,242 @@ func (c *Container) CreateWithAliases(aliases []string) error {
 
 // Start starts the container
 func (c *Container) Start() error {
-	err := c.Client.Docker.StartContainer(c.Container.ID, c.Container.HostConfig)
+	return c.StartWithContext(context.Background())
+}
+
+// StartWithContext starts the container
+// The start is aborted when the context is done
+func (c *Container) StartWithContext(ctx context.Context) error {
+	err := c.Client.Docker.StartContainerWithContext(c.Container.ID, c.Container.HostConfig, ctx)
 	if err != nil {
-		return fmt.Errorf("Can't start container %v because %v", c.ShortID(), err.Error())
+		return fmt.Errorf("Can't start container %v because %w", c.ShortID(), err)
 	}
-	c.Refresh()
+	c.RefreshWithContext(ctx)
 	return nil
 }
 
 // Run runs the container, aka pull image, create, start
 // If forcePull is true then the image will be pulled from the repository no matter if the image already exists on the machine or not
 func (c *Container) Run(forcePull bool) error {
+	return c.RunWithContext(context.Background(), forcePull)
+}
+
+// RunWithContext runs the container, aka pull image, create, start
+// If forcePull is true then the image will be pulled from the repository no matter if the image already exists on the machine or not
+// Every step is aborted when the context is done
+func (c *Container) RunWithContext(ctx context.Context, forcePull bool) error {
+	return c.RunWithProgress(ctx, forcePull, nil)
+}
+
+// RunWithProgress runs the container, aka pull image, create, start
+// If forcePull is true then the image will be pulled from the repository no matter if the image already exists on the machine or not
+// Progress of the image pull is sent to the progress function if not nil
+// Every step is aborted when the context is done
+func (c *Container) RunWithProgress(ctx context.Context, forcePull bool, progress func(PullProgress)) error {
+	return c.RunWithOptions(ctx, RunOptions{ForcePull: forcePull, Progress: progress})
+}
+
+// RunOptions defines how a container is run
+type RunOptions struct {
+	ForcePull   bool               // Pulls the image even if it already exists on the machine
+	Progress    func(PullProgress) // Receives the progress of the image pull, if not nil
+	WaitHealthy bool               // Waits until the container is healthy before returning, if it has a healthcheck
+}
+
+// RunWithOptions runs the container, aka pull image, create, start, and possibly waits until it is healthy
+// Every step is aborted when the context is done
+func (c *Container) RunWithOptions(ctx context.Context, opts RunOptions) error {
 	var err error
 
 	image := c.Image()
-	if forcePull || !c.Client.ImageExists(image) {
+	if opts.ForcePull || !c.Client.ImageExistsWithContext(ctx, image) {
 		log.Printf("Pulling %+v image\n", image)
-		err = c.Client.PullImage(image)
+		err = c.Client.PullImageWithProgress(ctx, image, opts.Progress)
 		if err != nil {
-			log.Println(err)
-			return fmt.Errorf("Unable to donwload %v image", image)
+			// The error holds the message of the engine (ex : manifest unknown)
+			return err
 		}
 	} else {
 		log.Printf("Image %+v already present\n", image)
 	}
 
 	log.Printf("Creating container %+v\n", c.Name())
-	err = c.Create()
+	err = c.CreateWithContext(ctx)
 	if err != nil {
-		log.Println(err)
-		return fmt.Errorf("Can't create container %+v", c.Name())
+		return fmt.Errorf("Can't create container %v : %w", c.Name(), err)
 	}
 
 	log.Printf("Starting container %+v\n", c.Name())
-	err = c.Start()
+	err = c.StartWithContext(ctx)
 	if err != nil {
-		log.Println(err)
-		return fmt.Errorf("Can't start %+v", c.Name())
+		return err
 	}
 
 	log.Printf("Container %v is started with id %v", c.Name(), c.ShortID())
 
+	if opts.WaitHealthy {
+		if _, err = c.WaitHealthy(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// stopTimeout computes the number of seconds the engine may wait before killing a stopping container
+// The context deadline is used, keeping a second for the kill itself. Returns false without deadline
+func stopTimeout(ctx context.Context) (uint, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	remaining := time.Until(deadline) - time.Second
+	if remaining <= 0 {
+		return 0, true
+	}
+	return uint(remaining / time.Second), true
+}
+
 // Stop stops a container
 func (c *Container) Stop() error {
-	err := c.Client.Docker.StopContainer(c.Container.ID, 30)
+	return c.StopWithContext(context.Background())
+}
+
+// StopWithContext stops a container
+// The engine waits until the context deadline (the stop timeout of the container without deadline) before killing the container
+func (c *Container) StopWithContext(ctx context.Context) error {
+	return c.StopWithOptions(ctx, StopOptions{})
+}
+
+// StopOptions defines how a container is stopped
+type StopOptions struct {
+	Timeout time.Duration // Time to wait before killing the container. Until the context deadline (the stop timeout of the container without deadline) if 0
+	Signal  string        // Signal sent to stop the container (ex : SIGINT). Stop signal of the container if empty
+}
+
+// StopWithOptions stops a container, with a custom timeout and stop signal
+func (c *Container) StopWithOptions(ctx context.Context, opts StopOptions) error {
+	query := url.Values{}
+	if timeout, ok := timeoutParam(ctx, opts.Timeout); ok {
+		query.Set("t", fmt.Sprint(timeout))
+	}
+	if opts.Signal != "" {
+		query.Set("signal", opts.Signal)
+	}
+	err := c.post(ctx, "stop", query)
+	if err != nil {
+		return fmt.Errorf("Can't stop container of id:%v (%w)", c.ShortID(), err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// Restart restarts a container
+func (c *Container) Restart(timeout time.Duration) error {
+	return c.RestartWithContext(context.Background(), timeout)
+}
+
+// RestartWithContext restarts a container
+// The engine waits for the timeout before killing the container, until the context deadline (the stop timeout of the container without deadline) if 0
+func (c *Container) RestartWithContext(ctx context.Context, timeout time.Duration) error {
+	query := url.Values{}
+	if timeout, ok := timeoutParam(ctx, timeout); ok {
+		query.Set("t", fmt.Sprint(timeout))
+	}
+	err := c.post(ctx, "restart", query)
+	if err != nil {
+		return fmt.Errorf("Can't restart container %v because %w", c.ShortID(), err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// Kill sends a signal to a container (ex : SIGHUP, 9). The container is killed with SIGKILL if the signal is empty
+func (c *Container) Kill(signal string) error {
+	return c.KillWithContext(context.Background(), signal)
+}
+
+// KillWithContext sends a signal to a container (ex : SIGHUP, 9). The container is killed with SIGKILL if the signal is empty
+func (c *Container) KillWithContext(ctx context.Context, signal string) error {
+	query := url.Values{}
+	if signal != "" {
+		query.Set("signal", signal)
+	}
+	err := c.post(ctx, "kill", query)
+	if err != nil {
+		return fmt.Errorf("Can't kill container %v because %w", c.ShortID(), err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// Pause suspends all processes of a container
+func (c *Container) Pause() error {
+	return c.PauseWithContext(context.Background())
+}
+
+// PauseWithContext suspends all processes of a container
+func (c *Container) PauseWithContext(ctx context.Context) error {
+	err := c.post(ctx, "pause", nil)
 	if err != nil {
-		return fmt.Errorf("Can't stop container of id:%v (%v)", c.ShortID(), err.Error())
+		return fmt.Errorf("Can't pause container %v because %w", c.ShortID(), err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// Unpause resumes all processes of a paused container
+func (c *Container) Unpause() error {
+	return c.UnpauseWithContext(context.Background())
+}
+
+// UnpauseWithContext resumes all processes of a paused container
+func (c *Container) UnpauseWithContext(ctx context.Context) error {
+	err := c.post(ctx, "unpause", nil)
+	if err != nil {
+		return fmt.Errorf("Can't unpause container %v because %w", c.ShortID(), err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// timeoutParam returns the number of seconds the engine may wait before killing a stopping container
+// The engine only accepts whole seconds, so a timeout is rounded up : a 500ms timeout must not kill the container at once
+// Returns false without timeout nor context deadline, so that the engine applies the stop timeout of the container
+func timeoutParam(ctx context.Context, timeout time.Duration) (uint, bool) {
+	if timeout > 0 {
+		return uint((timeout + time.Second - 1) / time.Second), true
+	}
+	return stopTimeout(ctx)
+}
+
+// post sends an action on the container to the engine (ex : stop, pause)
+// An action already done (ex : stopping a stopped container) is an error
+func (c *Container) post(ctx context.Context, action string, query url.Values) error {
+	if c.ID() == "" {
+		return errors.New("ID is empty")
+	}
+	resp, err := c.Client.request(ctx, http.MethodPost, "/containers/"+c.ID()+"/"+action, query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return fmt.Errorf("Container %v is already in the requested state", c.ShortID())
 	}
-	c.Refresh()
 	return nil
 }
 
 // Remove removes a container,
 // Volumes is a flag indicating whether Docker should remove the volumes associated to the container.
 func (c *Container) Remove(volumes bool) error {
+	return c.RemoveWithContext(context.Background(), volumes)
+}
+
+// RemoveWithContext removes a container,
+// Volumes is a flag indicating whether Docker should remove the volumes associated to the container.
+// The removal is aborted when the context is done
+func (c *Container) RemoveWithContext(ctx context.Context, volumes bool) error {
 
 	// Remove the container gracefull, then by force
 	superRemove := func(id string, volumes bool) error {
@@ -458,6 +790,7 @@ func (c *Container) Remove(volumes bool) error {
 				ID:            id,
 				Force:         false,
 				RemoveVolumes: volumes,
+				Context:       ctx,
 			}
 			// Graceful removal
 			err = c.Client.Docker.RemoveContainer(options)
@@ -473,7 +806,7 @@ func (c *Container) Remove(volumes bool) error {
 		} else {
 			err = errors.New("ID is empty")
 		}
-		return fmt.Errorf("Can't remove container with id %v -> %v)", id, err)
+		return fmt.Errorf("Can't remove container with id %v -> %w)", id, err)
 	}
 
 	err := superRemove(c.ID(), volumes)
@@ -481,154 +814,268 @@ func (c *Container) Remove(volumes bool) error {
 		return nil
 	}
 
-	return fmt.Errorf("Can't remove container %v (%v). Error : %v", c.Name(), c.ShortID(), err)
+	return fmt.Errorf("Can't remove container %v (%v). Error : %w", c.Name(), c.ShortID(), err)
 }
 
 // StopAndRemove stop and remove the container and possibly its volumes
 // Returns error if something bad happened
 func (c *Container) StopAndRemove(volumes bool) error {
-	err := c.Stop()
+	return c.StopAndRemoveWithContext(context.Background(), volumes)
+}
+
+// StopAndRemoveWithContext stop and remove the container and possibly its volumes
+// Returns error if something bad happened or if the context is done
+func (c *Container) StopAndRemoveWithContext(ctx context.Context, volumes bool) error {
+	err := c.StopWithContext(ctx)
 	if err != nil {
 		return err
 	}
-	return c.Remove(volumes)
+	return c.RemoveWithContext(ctx, volumes)
 }
 
-func exec(c SimpleContainer, client *Client, cmd []string) (logs []string, err error) {
+// WaitCondition is the condition a wait on a container is waiting for
+type WaitCondition string
+
+// Conditions of waits on containers
+const (
+	WaitNotRunning WaitCondition = "not-running" // Container is not running, returns immediately if it is already stopped
+	WaitNextExit   WaitCondition = "next-exit"   // Container exits the next time
+	WaitRemoved    WaitCondition = "removed"     // Container is removed
+)
+
+// WaitResult is the exit status of a container
+type WaitResult struct {
+	ExitCode  int    // Exit code of the container
+	OOMKilled bool   // True if the container was killed because it was out of memory. Unknown if it was already restarted
+	Error     string // Error message of the engine about the container (ex : it could not be started)
+}
+
+// Wait blocks until the container meets the condition (WaitNotRunning if empty), then returns its exit status
+// Stops waiting with an error when the context is done
+func (c *Container) Wait(ctx context.Context, condition WaitCondition) (WaitResult, error) {
 	if c.ID() == "" {
-		return logs, fmt.Errorf("Container %+v does not exist", c)
-	}
-
-	r, w := io.Pipe()
-	success := make(chan struct{})
-	createOptions := docker.CreateExecOptions{
-		AttachStdin:  false,
-		AttachStdout: true,
-		AttachStderr: true,
-		Tty:          false,
-		Cmd:          cmd,
-		Container:    c.ID(),
-	}
-	execOptions := docker.StartExecOptions{
-		Detach:       false,
-		Tty:          false,
-		OutputStream: w,
-		ErrorStream:  w,
-		RawTerminal:  false,
-		Success:      success,
-	}
-	exec, err := client.Docker.CreateExec(createOptions)
+		return WaitResult{}, fmt.Errorf("Can't wait for container %v because ID is empty", c.Name())
+	}
+	if condition == "" {
+		condition = WaitNotRunning
+	}
+	query := url.Values{}
+	query.Set("condition", string(condition))
+	resp, err := c.Client.request(ctx, http.MethodPost, "/containers/"+c.ID()+"/wait", query, nil)
 	if err != nil {
-		return logs, err
+		return WaitResult{}, fmt.Errorf("Can't wait for container %v because %w", c.ShortID(), err)
 	}
+	defer resp.Body.Close()
 
-	go func() {
-		defer r.Close()
-		if errr := client.Docker.StartExec(exec.ID, execOptions); errr != nil {
-			log.Fatal(errr)
-			err = errr
+	var status struct {
+		StatusCode int
+		Error      *struct {
+			Message string
 		}
-	}()
-	<-success
-	close(success)
-	if err != nil {
-		return logs, err
 	}
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		logs = append(logs, scanner.Text())
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return WaitResult{}, fmt.Errorf("Can't wait for container %v because %w", c.ShortID(), err)
+	}
+	res := WaitResult{ExitCode: status.StatusCode}
+	if status.Error != nil {
+		res.Error = status.Error.Message
+	}
+	if condition == WaitRemoved {
+		return res, nil
 	}
 
-	execInspect, err := client.Docker.InspectExec(exec.ID)
-	if err != nil {
-		return logs, err
+	// The exit code of the wait is kept : a container with a restart policy may already be restarted, with a new state
+	// The state of a container still stopped holds more details
+	if err := c.RefreshWithContext(ctx); err != nil || c.Container.State.Running {
+		return res, nil
 	}
-	if execInspect.ExitCode != 0 {
-		return logs, fmt.Errorf("Command %q failed : %v ", strings.Join(cmd, " "), execInspect.ExitCode)
+	res.OOMKilled = c.Container.State.OOMKilled
+	if c.Container.State.Error != "" {
+		res.Error = c.Container.State.Error
 	}
+	return res, nil
+}
 
-	return logs, nil
+// PoolContainer is a pool of container. Can do mass operations on this
+type PoolContainer []*Container
+
+// RunAll runs all containers from the pool
+// Returns a PoolResult as error if a container failed
+// If forcePull is true then images will be pulled from the repository no matter if the image already exists on the machine or not
+func (pool PoolContainer) RunAll(forcePull bool) error {
+	return pool.RunAllWithContext(context.Background(), forcePull)
+}
+
+// RunAllWithContext runs all containers from the pool
+// Returns a PoolResult as error if a container failed
+// If forcePull is true then images will be pulled from the repository no matter if the image already exists on the machine or not
+// Containers not yet started when the context is done are aborted
+func (pool PoolContainer) RunAllWithContext(ctx context.Context, forcePull bool) error {
+	return pool.RunAllWithOptions(ctx, forcePull, PoolOptions{})
+}
+
+// RunAllWithOptions runs all containers from the pool, within the limits of the options
+// Containers are started after the containers they depend on (links and DependsOn), the others at the same time
+// Returns a PoolResult as error if a container failed, or a DependencyCycleError
+// Each image is pulled once, even when used by several containers
+// Containers not yet started when the context is done are aborted
+func (pool PoolContainer) RunAllWithOptions(ctx context.Context, forcePull bool, opts PoolOptions) error {
+	result, err := pool.runInOrder(ctx, newImagePuller(forcePull, opts.PullWorkers), opts, false)
+	if err != nil {
+		return err
+	}
+	return result.Err()
 }
 
-// ExecSh executes a command in sh shell on a container
-func (c *Container) ExecSh(cmd []string) (logs []string, err error) {
-	shell := []string{"/bin/sh", "-c"}
-	return c.Exec(append(shell, cmd...))
+// runInOrder pulls the images, creates and starts the containers, after the containers they depend on
+// With WaitHealthy, a container is healthy before the containers depending on it are started
+// Containers without dependents are also waited for with everyContainer, so that an unhealthy container is a failure
+func (pool PoolContainer) runInOrder(ctx context.Context, puller *imagePuller, opts PoolOptions, everyContainer bool) (PoolResult, error) {
+	return pool.eachInOrder(ctx, opts, false, func(c *Container) error {
+		if err := puller.pull(ctx, c); err != nil {
+			return err
+		}
+		if err := c.RunWithContext(ctx, false); err != nil {
+			return err
+		}
+		if opts.WaitHealthy && (everyContainer || pool.hasDependents(c)) {
+			_, err := c.WaitHealthy(ctx)
+			return err
+		}
+		return nil
+	})
 }
 
-// Exec executes a command on a container
-func (c *Container) Exec(cmd []string) (logs []string, err error) {
-	return exec(c, c.Client, cmd)
+// RemoveAll stops and remove all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) RemoveAll(volumes bool) error {
+	return pool.RemoveAllWithContext(context.Background(), volumes)
 }
 
-// LogsOptions is used to get logs from container
-type LogsOptions struct {
-	OutputStream io.Writer
-	ErrorStream  io.Writer
-	Stdout       bool
-	Stderr       bool
-	Tail         string
+// RemoveAllWithContext stops and remove all containers from the pool
+// Returns a PoolResult as error if a container failed
+// Containers not yet removed when the context is done are left untouched
+func (pool PoolContainer) RemoveAllWithContext(ctx context.Context, volumes bool) error {
+	return pool.RemoveAllWithOptions(ctx, volumes, PoolOptions{})
 }
 
-// Logs get the logs from the container
-func (c *Container) Logs(opts LogsOptions) error {
-	err := c.Client.Docker.Logs(docker.LogsOptions{
-		Container:    c.ID(),
-		OutputStream: opts.OutputStream,
-		ErrorStream:  opts.ErrorStream,
-		Stderr:       opts.Stderr,
-		Stdout:       opts.Stdout,
-		Tail:         opts.Tail,
-		Follow:       true,
-		Timestamps:   true,
+// RemoveAllWithOptions stops and remove all containers from the pool, within the limits of the options
+// Containers are removed before the containers they depend on
+// Returns a PoolResult as error if a container failed, or a DependencyCycleError
+// Containers not yet removed when the context is done are left untouched
+func (pool PoolContainer) RemoveAllWithOptions(ctx context.Context, volumes bool, opts PoolOptions) error {
+	result, err := pool.eachInOrder(ctx, opts, true, func(c *Container) error {
+		return c.RemoveWithContext(ctx, volumes)
 	})
 	if err != nil {
-		return fmt.Errorf("Can't get logs from container %v because : %v", c.ShortID(), err.Error())
+		return err
 	}
-	return nil
+	return result.Err()
 }
 
-// PoolContainer is a pool of container. Can do mass operations on this
-type PoolContainer []*Container
+// WaitAll waits until all containers from the pool meet the condition (WaitNotRunning if empty)
+// Returns the exit status of each container, by name. Returns a PoolResult as error if a container failed
+func (pool PoolContainer) WaitAll(ctx context.Context, condition WaitCondition) (results map[string]WaitResult, err error) {
+	type waited struct {
+		name   string
+		result WaitResult
+		err    error
+	}
 
-// RunAll runs all containers from the pool
-// Returns error if something bad happened but no error exits
-// If forcePull is true then images will be pulled from the repository no matter if the image already exists on the machine or not
-func (pool PoolContainer) RunAll(forcePull bool) (err error) {
-	sem := make(chan error, len(pool))
-	// Concurrent Run
+	// Concurrent Wait
+	sem := make(chan waited, len(pool))
 	for _, v := range pool {
 		go func(v *Container) {
-			sem <- v.Run(forcePull)
+			res, err := v.Wait(ctx, condition)
+			sem <- waited{v.Name(), res, err}
 		}(v)
 	}
 	// Waiting for return
+	results = map[string]WaitResult{}
+	outcomes := PoolResult{}
 	for i := 0; i < len(pool); i++ {
-		err = <-sem
-		if err != nil {
-			log.Println(err)
+		w := <-sem
+		if w.err != nil {
+			log.Println(w.err)
+			outcomes[w.name] = PoolContainerResult{Outcome: PoolFailed, Err: w.err}
+			continue
 		}
+		outcomes[w.name] = PoolContainerResult{Outcome: PoolSucceeded}
+		results[w.name] = w.result
 	}
-	return
+	return results, outcomes.Err()
 }
 
-// RemoveAll stops and remove all containers from the pool
-// Returns error if something bad happened but no error exits
-func (pool PoolContainer) RemoveAll(volumes bool) (err error) {
+// StopAll stops all containers from the pool, before the containers they depend on
+// Returns a PoolResult as error if a container failed, or a DependencyCycleError
+func (pool PoolContainer) StopAll(opts StopOptions) error {
+	return pool.StopAllWithContext(context.Background(), opts)
+}
 
-	// Concurrent Remove
-	sem := make(chan error, len(pool))
-	for _, v := range pool {
-		go func(v *Container) {
-			sem <- v.Remove(volumes)
-		}(v)
-	}
-	// Waiting for return
-	for i := 0; i < len(pool); i++ {
-		err = <-sem
-		if err != nil {
-			log.Println(err)
-		}
+// StopAllWithContext stops all containers from the pool, before the containers they depend on
+// Returns a PoolResult as error if a container failed, or a DependencyCycleError
+func (pool PoolContainer) StopAllWithContext(ctx context.Context, opts StopOptions) error {
+	result, err := pool.eachInOrder(ctx, PoolOptions{}, true, func(c *Container) error {
+		return c.StopWithOptions(ctx, opts)
+	})
+	if err != nil {
+		return err
 	}
-	return err
+	return result.Err()
+}
+
+// RestartAll restarts all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) RestartAll(timeout time.Duration) error {
+	return pool.RestartAllWithContext(context.Background(), timeout)
+}
+
+// RestartAllWithContext restarts all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) RestartAllWithContext(ctx context.Context, timeout time.Duration) error {
+	return pool.each(func(c *Container) error {
+		return c.RestartWithContext(ctx, timeout)
+	}).Err()
+}
+
+// KillAll sends a signal to all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) KillAll(signal string) error {
+	return pool.KillAllWithContext(context.Background(), signal)
+}
+
+// KillAllWithContext sends a signal to all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) KillAllWithContext(ctx context.Context, signal string) error {
+	return pool.each(func(c *Container) error {
+		return c.KillWithContext(ctx, signal)
+	}).Err()
+}
+
+// PauseAll pauses all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) PauseAll() error {
+	return pool.PauseAllWithContext(context.Background())
+}
+
+// PauseAllWithContext pauses all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) PauseAllWithContext(ctx context.Context) error {
+	return pool.each(func(c *Container) error {
+		return c.PauseWithContext(ctx)
+	}).Err()
+}
+
+// UnpauseAll unpauses all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) UnpauseAll() error {
+	return pool.UnpauseAllWithContext(context.Background())
+}
+
+// UnpauseAllWithContext unpauses all containers from the pool
+// Returns a PoolResult as error if a container failed
+func (pool PoolContainer) UnpauseAllWithContext(ctx context.Context) error {
+	return pool.each(func(c *Container) error {
+		return c.UnpauseWithContext(ctx)
+	}).Err()
 }
@@ -0,0 +1,313 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewContainerConfig(t *testing.T) {
+	client := &Client{}
+	c, err := client.NewContainer(ContainerOptions{
+		Image:       "nginx:latest",
+		Name:        "web",
+		Entrypoint:  []string{"/docker-entrypoint.sh"},
+		User:        "101:101",
+		WorkingDir:  "/usr/share/nginx",
+		StopSignal:  "SIGQUIT",
+		StopTimeout: 20 * time.Second,
+		Tty:         true,
+		OpenStdin:   true,
+		Domainname:  "example.com",
+		DependsOn:   []string{"api"},
+	})
+	assert.NoError(t, err)
+
+	clone, err := c.Clone()
+	assert.NoError(t, err)
+	for _, v := range []*Container{c, clone} {
+		config := v.Container.Config
+		assert.Equal(t, []string{"/docker-entrypoint.sh"}, config.Entrypoint)
+		assert.Equal(t, "101:101", config.User)
+		assert.Equal(t, "/usr/share/nginx", config.WorkingDir)
+		assert.Equal(t, "SIGQUIT", config.StopSignal)
+		assert.Equal(t, 20, config.StopTimeout)
+		assert.True(t, config.Tty)
+		assert.True(t, config.OpenStdin)
+		assert.Equal(t, "example.com", config.Domainname)
+		assert.Equal(t, []string{"api"}, v.DependsOn)
+	}
+}
+
+func TestNewContainerConfigErrors(t *testing.T) {
+	client := &Client{}
+	_, err := client.NewContainer(ContainerOptions{Image: "nginx", Name: "web", WorkingDir: "html"})
+	assert.EqualError(t, err, `Working directory "html" must be absolute`)
+	_, err = client.NewContainer(ContainerOptions{Image: "nginx", Name: "web", StopTimeout: 1500 * time.Millisecond})
+	assert.EqualError(t, err, "Stop timeout must be a positive number of seconds, got 1.5s")
+}
+
+func TestNewContainerNetworks(t *testing.T) {
+	client := &Client{}
+	c, err := client.NewContainer(ContainerOptions{
+		Image: "nginx",
+		Name:  "web",
+		Networks: []NetworkAttachment{
+			{Network: "front", EndpointOptions: EndpointOptions{Aliases: []string{"www"}}},
+			{Network: "back", EndpointOptions: EndpointOptions{IPv4Address: "172.28.0.10"}},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "front", c.Container.HostConfig.NetworkMode)
+	assert.Len(t, c.Networks, 2)
+
+	tests := map[string][]NetworkAttachment{
+		"Network host can't be attached, use NetworkMode instead":                  {{Network: "host"}},
+		"Network back is attached twice":                                           {{Network: "back"}, {Network: "back"}},
+		`Invalid attachment to network back : Invalid IPv4 address "172.28.0.300"`: {{Network: "back", EndpointOptions: EndpointOptions{IPv4Address: "172.28.0.300"}}},
+	}
+	for expected, networks := range tests {
+		_, err := client.NewContainer(ContainerOptions{Image: "nginx", Name: "web", Networks: networks})
+		assert.EqualError(t, err, expected)
+	}
+	_, err = client.NewContainer(ContainerOptions{Image: "nginx", Name: "web", NetworkMode: "bridge", Networks: []NetworkAttachment{{Network: "front"}}})
+	assert.EqualError(t, err, "Network mode bridge can't be used with network attachments")
+}
+
+func TestWait(t *testing.T) {
+	inspect := http.StatusOK
+	state := `{"ExitCode":137,"OOMKilled":true,"Error":"out of memory"}`
+	var conditions []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/0123456789ab/wait":
+			conditions = append(conditions, r.URL.Query().Get("condition"))
+			w.Write([]byte(`{"StatusCode":137,"Error":{"Message":"wait failed"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/0123456789ab/json":
+			if inspect != http.StatusOK {
+				http.Error(w, "boom", inspect)
+				return
+			}
+			w.Write([]byte(`{"Id":"0123456789ab","Name":"/web","State":` + state + `}`))
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	c := &Container{Container: &docker.Container{ID: "0123456789ab", Name: "/web"}, Client: client}
+
+	res, err := c.Wait(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, WaitResult{ExitCode: 137, OOMKilled: true, Error: "out of memory"}, res)
+
+	// A container restarted by its restart policy keeps the exit code of the wait
+	state = `{"Running":true,"ExitCode":0}`
+	res, err = c.Wait(context.Background(), WaitNextExit)
+	assert.NoError(t, err)
+	assert.Equal(t, WaitResult{ExitCode: 137, Error: "wait failed"}, res)
+
+	// The result of the wait is kept when the container can't be refreshed
+	inspect = http.StatusInternalServerError
+	res, err = c.Wait(context.Background(), WaitNextExit)
+	assert.NoError(t, err)
+	assert.Equal(t, WaitResult{ExitCode: 137, Error: "wait failed"}, res)
+
+	// A removed container is not refreshed
+	res, err = c.Wait(context.Background(), WaitRemoved)
+	assert.NoError(t, err)
+	assert.Equal(t, WaitResult{ExitCode: 137, Error: "wait failed"}, res)
+	assert.Equal(t, []string{"not-running", "next-exit", "next-exit", "removed"}, conditions)
+
+	_, err = (&Container{Container: &docker.Container{Name: "/db"}, Client: client}).Wait(context.Background(), "")
+	assert.EqualError(t, err, "Can't wait for container db because ID is empty")
+}
+
+func TestLifecycle(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/0123456789ab/pause":
+			requests = append(requests, "pause")
+			w.WriteHeader(http.StatusNotModified)
+		case r.Method == http.MethodPost:
+			requests = append(requests, strings.TrimPrefix(r.URL.Path, "/containers/0123456789ab/")+"?"+r.URL.RawQuery)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/0123456789ab/json":
+			w.Write([]byte(`{"Id":"0123456789ab","Name":"/web","State":{"Running":true}}`))
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	c := &Container{Container: &docker.Container{ID: "0123456789ab", Name: "/web"}, Client: client}
+
+	assert.NoError(t, c.StopWithOptions(context.Background(), StopOptions{Timeout: 10 * time.Second, Signal: "SIGINT"}))
+	assert.True(t, c.IsRunning(), "container is refreshed after the action")
+	// Without timeout nor deadline, the engine applies the stop timeout of the container
+	assert.NoError(t, c.Stop())
+	assert.NoError(t, c.Restart(1500*time.Millisecond))
+	assert.NoError(t, c.Kill("SIGHUP"))
+	assert.NoError(t, c.Kill(""))
+	assert.NoError(t, c.Unpause())
+	assert.EqualError(t, c.Pause(), "Can't pause container 0123456789ab because Container 0123456789ab is already in the requested state")
+	assert.Equal(t, []string{
+		"stop?" + url.Values{"t": {"10"}, "signal": {"SIGINT"}}.Encode(),
+		"stop?",
+		"restart?t=2",
+		"kill?signal=SIGHUP",
+		"kill?",
+		"unpause?",
+		"pause",
+	}, requests)
+
+	empty := &Container{Container: &docker.Container{Name: "/db"}, Client: client}
+	assert.EqualError(t, empty.Stop(), "Can't stop container of id: (ID is empty)")
+	assert.EqualError(t, empty.Kill(""), "Can't kill container  because ID is empty")
+	assert.Len(t, requests, 7)
+}
+
+func TestTimeoutParam(t *testing.T) {
+	timeout, ok := timeoutParam(context.Background(), 10*time.Second)
+	assert.Equal(t, uint(10), timeout)
+	assert.True(t, ok)
+	timeout, _ = timeoutParam(context.Background(), 500*time.Millisecond)
+	assert.Equal(t, uint(1), timeout)
+	timeout, _ = timeoutParam(context.Background(), 1001*time.Millisecond)
+	assert.Equal(t, uint(2), timeout)
+
+	// The stop timeout of the container applies without deadline
+	_, ok = timeoutParam(context.Background(), 0)
+	assert.False(t, ok)
+	ctx, cancel := context.WithTimeout(context.Background(), 20500*time.Millisecond)
+	defer cancel()
+	timeout, ok = timeoutParam(ctx, 0)
+	assert.Equal(t, uint(19), timeout)
+	assert.True(t, ok)
+}
+
+func TestRunAllWrapsErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/version":
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/images/redis/json":
+			w.Write([]byte(`{"Id":"sha256:0123"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/create" && r.URL.Query().Get("name") == "broken":
+			http.Error(w, `{"message":"invalid mount config"}`, http.StatusInternalServerError)
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"0123456789ab"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/0123456789ab/start":
+			// The caller gives up while the engine is starting the container
+			cancel()
+			<-r.Context().Done()
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	broken, err := client.NewContainer(ContainerOptions{Image: "redis", Name: "broken"})
+	assert.NoError(t, err)
+	err = PoolContainer{broken}.RunAllWithOptions(context.Background(), false, PoolOptions{})
+	assert.EqualError(t, err, "1 of 1 containers failed : broken: Can't create container broken : API error (500): invalid mount config")
+	var apiErr *docker.Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.Status)
+
+	cache, err := client.NewContainer(ContainerOptions{Image: "redis", Name: "cache"})
+	assert.NoError(t, err)
+	err = PoolContainer{cache}.RunAllWithOptions(ctx, false, PoolOptions{})
+	assert.True(t, errors.Is(err, context.Canceled), "got %v", err)
+}
+
+func TestCreateRemovesContainerWhenNetworkFails(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	removal := http.StatusNoContent
+	var removals int
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"0123456789ab"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/0123456789ab/json":
+			w.Write([]byte(`{"Id":"0123456789ab","Name":"/web"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/networks/back/connect":
+			// The caller gives up once the connection failed
+			cancel()
+			http.Error(w, `{"message":"network back not found"}`, http.StatusInternalServerError)
+		case r.Method == http.MethodDelete && r.URL.Path == "/containers/0123456789ab":
+			removals++
+			if removal != http.StatusNoContent {
+				http.Error(w, `{"message":"removal already in progress"}`, removal)
+				return
+			}
+			w.WriteHeader(removal)
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	c, err := client.NewContainer(ContainerOptions{
+		Image:    "nginx",
+		Name:     "web",
+		Networks: []NetworkAttachment{{Network: "front"}, {Network: "back"}},
+	})
+	assert.NoError(t, err)
+
+	// The container is removed even though the context is done
+	err = c.CreateWithContext(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, removals)
+	assert.Empty(t, c.ID())
+
+	removal = http.StatusConflict
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	err = c.CreateWithContext(ctx)
+	assert.Contains(t, err.Error(), "Can't connect container web to network back")
+	assert.Contains(t, err.Error(), "and the container can't be removed : Can't remove container web (0123456789ab)")
+	var apiErr *docker.Error
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusConflict, apiErr.Status)
+	assert.Equal(t, 3, removals, "graceful then forced removal")
+}
+
+func TestPoolLifecycleErrors(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/wait"):
+			// The container never stops
+			<-r.Context().Done()
+		case r.Method == http.MethodPost:
+			http.Error(w, `{"message":"cannot do it"}`, http.StatusConflict)
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	c := &Container{Container: &docker.Container{ID: "0123456789ab", Name: "/web"}, Client: client}
+	pool := PoolContainer{c}
+
+	for action, err := range map[string]error{
+		"stop":    pool.StopAll(StopOptions{}),
+		"restart": pool.RestartAll(0),
+		"kill":    pool.KillAll(""),
+		"pause":   pool.PauseAll(),
+		"unpause": pool.UnpauseAll(),
+	} {
+		var apiErr *docker.Error
+		if assert.True(t, errors.As(err, &apiErr), action) {
+			assert.Equal(t, http.StatusConflict, apiErr.Status, action)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := pool.WaitAll(ctx, "")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "got %v", err)
+}
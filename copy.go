@@ -0,0 +1,188 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// CopyFileOptions defines the attributes of a file copied into a container
+type CopyFileOptions struct {
+	Mode    os.FileMode // Permissions of the file. 0644 if 0
+	UID     int         // Owner of the file. root if 0
+	GID     int         // Group of the file. root if 0
+	ModTime time.Time   // Modification time of the file. Now if zero
+}
+
+// CopyTo extracts a tar archive into a directory of the container, which must exist
+// Works on running and stopped containers
+func (c *Container) CopyTo(ctx context.Context, containerPath string, archive io.Reader) error {
+	err := c.Client.Docker.UploadToContainer(c.ID(), docker.UploadToContainerOptions{
+		InputStream: archive,
+		Path:        containerPath,
+		Context:     ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("Can't copy to %v in container %v because %v", containerPath, c.Name(), err.Error())
+	}
+	return nil
+}
+
+// CopyFrom returns a tar archive of a file or directory of the container, to close once read
+// The archive holds the file or directory itself, named after the last element of the path
+// Works on running and stopped containers
+func (c *Container) CopyFrom(ctx context.Context, containerPath string) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("path", containerPath)
+	resp, err := c.Client.request(ctx, http.MethodGet, "/containers/"+c.ID()+"/archive", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Can't copy %v from container %v because %v", containerPath, c.Name(), err.Error())
+	}
+	return resp.Body, nil
+}
+
+// CopyFileTo writes a file of the container, with the content and attributes given
+// The directory of the file must exist
+func (c *Container) CopyFileTo(ctx context.Context, containerPath string, content []byte, opts CopyFileOptions) error {
+	archive, err := fileArchive(path.Base(containerPath), content, opts)
+	if err != nil {
+		return err
+	}
+	return c.CopyTo(ctx, path.Dir(containerPath), archive)
+}
+
+// CopyDirTo copies the content of a local directory into a directory of the container, which must exist
+// Permissions, symbolic links and modification times are kept. Owners are those of the local files
+func (c *Container) CopyDirTo(ctx context.Context, localDir, containerPath string) error {
+	if info, err := os.Stat(localDir); err != nil || !info.IsDir() {
+		return fmt.Errorf("Can't copy %v because it is not a directory", localDir)
+	}
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeDirArchive(writer, localDir))
+	}()
+	defer reader.Close()
+	return c.CopyTo(ctx, containerPath, reader)
+}
+
+// CopyFileFrom reads a regular file of the container
+func (c *Container) CopyFileFrom(ctx context.Context, containerPath string) ([]byte, error) {
+	archive, err := c.CopyFrom(ctx, containerPath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+	content, err := readFileArchive(archive)
+	if err != nil {
+		return nil, fmt.Errorf("Can't copy %v from container %v because %v", containerPath, c.Name(), err.Error())
+	}
+	return content, nil
+}
+
+// fileArchive builds a tar archive of a single file
+func fileArchive(name string, content []byte, opts CopyFileOptions) (io.Reader, error) {
+	if name == "" || name == "/" || name == "." {
+		return nil, errors.New("File name is required")
+	}
+	if opts.Mode == 0 {
+		opts.Mode = 0644
+	}
+	if opts.ModTime.IsZero() {
+		opts.ModTime = time.Now()
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     int64(opts.Mode.Perm()),
+		Uid:      opts.UID,
+		Gid:      opts.GID,
+		Size:     int64(len(content)),
+		ModTime:  opts.ModTime,
+	})
+	if err == nil {
+		_, err = tw.Write(content)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// writeDirArchive writes a tar archive of the content of a local directory
+func writeDirArchive(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readFileArchive reads the content of the regular file of an archive holding a single file
+func readFileArchive(r io.Reader) ([]byte, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err == io.EOF {
+		return nil, errors.New("archive is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if header.Typeflag != tar.TypeReg {
+		return nil, fmt.Errorf("%v is not a regular file", header.Name)
+	}
+	return io.ReadAll(tr)
+}
@@ -0,0 +1,61 @@
+package dockerapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileArchive(t *testing.T) {
+	archive, err := fileArchive("nginx.conf", []byte("worker_processes 1;"), CopyFileOptions{Mode: 0600, UID: 101, GID: 101})
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(archive)
+
+	tr := tar.NewReader(bytes.NewReader(content))
+	header, err := tr.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "nginx.conf", header.Name)
+	assert.Equal(t, int64(0600), header.Mode)
+	assert.Equal(t, 101, header.Uid)
+
+	file, err := readFileArchive(bytes.NewReader(content))
+	assert.NoError(t, err)
+	assert.Equal(t, "worker_processes 1;", string(file))
+
+	_, err = fileArchive("/", nil, CopyFileOptions{})
+	assert.EqualError(t, err, "File name is required")
+}
+
+func TestDirArchive(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "default.conf"), []byte("server {}"), 0640))
+	assert.NoError(t, os.Symlink("conf.d/default.conf", filepath.Join(dir, "current.conf")))
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeDirArchive(&buf, dir))
+
+	entries := map[string]*tar.Header{}
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		entries[header.Name] = header
+	}
+	assert.Len(t, entries, 3)
+	assert.Equal(t, byte(tar.TypeDir), entries["conf.d/"].Typeflag)
+	assert.Equal(t, int64(0640), entries["conf.d/default.conf"].Mode)
+	assert.Equal(t, int64(9), entries["conf.d/default.conf"].Size)
+	assert.Equal(t, "conf.d/default.conf", entries["current.conf"].Linkname)
+
+	_, err := readFileArchive(bytes.NewReader(nil))
+	assert.EqualError(t, err, "archive is empty")
+}
@@ -0,0 +1,159 @@
+package dockerapi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DependencyCycleError is returned when containers of a pool depend on each other
+type DependencyCycleError struct {
+	Cycle []string // Names of the containers of the cycle, the first one being repeated at the end
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("Dependency cycle between containers : %v", strings.Join(e.Cycle, " -> "))
+}
+
+// Dependencies returns the names of the containers from the pool the container depends on, from its links and DependsOn
+// Dependencies on containers outside of the pool are ignored
+func (pool PoolContainer) Dependencies(c *Container) []string {
+	names := map[string]bool{}
+	for _, v := range pool {
+		names[v.Name()] = true
+	}
+
+	candidates := append([]string{}, c.DependsOn...)
+	if c.Container != nil && c.Container.HostConfig != nil {
+		for _, link := range c.Container.HostConfig.Links {
+			// Format : externalname:internalname, or /externalname:/name/internalname once inspected
+			name, _, _ := strings.Cut(link, ":")
+			candidates = append(candidates, strings.TrimPrefix(name, "/"))
+		}
+	}
+
+	deps := []string{}
+	seen := map[string]bool{}
+	for _, name := range candidates {
+		if names[name] && !seen[name] && name != c.Name() {
+			seen[name] = true
+			deps = append(deps, name)
+		}
+	}
+	return deps
+}
+
+// Waves sorts the containers of the pool in waves : containers of a wave only depend on containers of the previous waves
+// Containers keep the order of the pool inside a wave
+// Returns a DependencyCycleError if containers depend on each other
+func (pool PoolContainer) Waves() ([]PoolContainer, error) {
+	deps := map[string][]string{}
+	for _, c := range pool {
+		deps[c.Name()] = pool.Dependencies(c)
+	}
+
+	waves := []PoolContainer{}
+	done := map[string]bool{}
+	remaining := pool
+	for len(remaining) > 0 {
+		wave, next := PoolContainer{}, PoolContainer{}
+		for _, c := range remaining {
+			ready := true
+			for _, dep := range deps[c.Name()] {
+				ready = ready && done[dep]
+			}
+			if ready {
+				wave = append(wave, c)
+			} else {
+				next = append(next, c)
+			}
+		}
+		if len(wave) == 0 {
+			return nil, &DependencyCycleError{Cycle: findCycle(next, deps)}
+		}
+		for _, c := range wave {
+			done[c.Name()] = true
+		}
+		waves = append(waves, wave)
+		remaining = next
+	}
+	return waves, nil
+}
+
+// findCycle returns a cycle among the containers, which all have at least one dependency among them
+func findCycle(containers PoolContainer, deps map[string][]string) []string {
+	blocked := map[string]bool{}
+	for _, c := range containers {
+		blocked[c.Name()] = true
+	}
+
+	// Following blocked dependencies necessarily loops
+	path := []string{}
+	index := map[string]int{}
+	name := containers[0].Name()
+	for {
+		if i, ok := index[name]; ok {
+			return append(path[i:], name)
+		}
+		index[name] = len(path)
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if blocked[dep] {
+				name = dep
+				break
+			}
+		}
+	}
+}
+
+// eachInOrder runs the action on the containers of the pool wave after wave, following their dependencies
+// Containers are handled before the containers depending on them, or after them if reverse is true
+// In dependency order, a container whose dependency failed is not handled and fails too
+func (pool PoolContainer) eachInOrder(ctx context.Context, opts PoolOptions, reverse bool, action func(c *Container) error) (PoolResult, error) {
+	waves, err := pool.Waves()
+	if err != nil {
+		return nil, err
+	}
+	if reverse {
+		for i, j := 0, len(waves)-1; i < j; i, j = i+1, j-1 {
+			waves[i], waves[j] = waves[j], waves[i]
+		}
+	}
+
+	result := PoolResult{}
+	for _, wave := range waves {
+		ready := PoolContainer{}
+		for _, c := range wave {
+			failed := ""
+			if !reverse {
+				for _, dep := range pool.Dependencies(c) {
+					if result[dep].Outcome == PoolFailed {
+						failed = dep
+						break
+					}
+				}
+			}
+			if failed != "" {
+				result[c.Name()] = PoolContainerResult{Outcome: PoolFailed, Err: fmt.Errorf("Can't handle container %v because its dependency %v failed", c.Name(), failed)}
+				continue
+			}
+			ready = append(ready, c)
+		}
+		for name, res := range ready.eachWithOptions(ctx, opts, action) {
+			result[name] = res
+		}
+	}
+	return result, nil
+}
+
+// hasDependents checks whether containers from the pool depend on the container
+func (pool PoolContainer) hasDependents(c *Container) bool {
+	for _, v := range pool {
+		for _, dep := range pool.Dependencies(v) {
+			if dep == c.Name() {
+				return true
+			}
+		}
+	}
+	return false
+}
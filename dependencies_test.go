@@ -0,0 +1,96 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPoolTestContainer(name string, links []string, dependsOn ...string) *Container {
+	return &Container{
+		Container: &docker.Container{Name: name, HostConfig: &docker.HostConfig{Links: links}},
+		DependsOn: dependsOn,
+	}
+}
+
+func waveNames(waves []PoolContainer) [][]string {
+	res := [][]string{}
+	for _, wave := range waves {
+		names := []string{}
+		for _, c := range wave {
+			names = append(names, c.Name())
+		}
+		res = append(res, names)
+	}
+	return res
+}
+
+func TestWaves(t *testing.T) {
+	pool := PoolContainer{
+		newPoolTestContainer("web", []string{"api:api"}),
+		newPoolTestContainer("api", []string{"/db:/api/db"}, "cache"),
+		newPoolTestContainer("db", nil, "external"),
+		newPoolTestContainer("cache", nil),
+		newPoolTestContainer("worker", nil, "db"),
+	}
+	assert.Equal(t, []string{"cache", "db"}, pool.Dependencies(pool[1]))
+	assert.Empty(t, pool.Dependencies(pool[2]))
+
+	waves, err := pool.Waves()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"db", "cache"}, {"api", "worker"}, {"web"}}, waveNames(waves))
+}
+
+func TestWavesCycle(t *testing.T) {
+	pool := PoolContainer{
+		newPoolTestContainer("cache", nil),
+		newPoolTestContainer("web", nil, "api"),
+		newPoolTestContainer("api", []string{"db:db"}),
+		newPoolTestContainer("db", nil, "web", "cache"),
+	}
+	_, err := pool.Waves()
+	var cycle *DependencyCycleError
+	assert.True(t, errors.As(err, &cycle))
+	assert.Equal(t, []string{"web", "api", "db", "web"}, cycle.Cycle)
+	assert.EqualError(t, err, "Dependency cycle between containers : web -> api -> db -> web")
+}
+
+func TestEachInOrder(t *testing.T) {
+	pool := PoolContainer{
+		newPoolTestContainer("web", nil, "api"),
+		newPoolTestContainer("api", nil, "db"),
+		newPoolTestContainer("db", nil),
+		newPoolTestContainer("cache", nil),
+	}
+
+	var mu sync.Mutex
+	order := []string{}
+	record := func(fail string) func(c *Container) error {
+		return func(c *Container) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, c.Name())
+			if c.Name() == fail {
+				return errors.New("boom")
+			}
+			return nil
+		}
+	}
+
+	result, err := pool.eachInOrder(context.Background(), PoolOptions{Workers: 1}, true, record(""))
+	assert.NoError(t, err)
+	assert.NoError(t, result.Err())
+	assert.Equal(t, "web", order[0])
+	assert.Equal(t, "api", order[1])
+
+	order = []string{}
+	result, err = pool.eachInOrder(context.Background(), PoolOptions{}, false, record("api"))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"db", "cache", "api"}, order)
+	assert.Equal(t, []string{"api", "web"}, result.Failed())
+	assert.EqualError(t, result["web"].Err, "Can't handle container web because its dependency api failed")
+}
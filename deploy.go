@@ -0,0 +1,215 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// deployBackupSuffix is appended to the names of the containers replaced by a deployment, until it succeeds
+const deployBackupSuffix = "_previous"
+
+// RollbackAction is an action taken to roll back a failed deployment
+type RollbackAction struct {
+	Container string // Name of the container of the pool
+	Action    string // Description of the action (ex : removed new container 0123456789ab)
+	Err       error  // Error of the action, if it failed
+}
+
+func (a RollbackAction) String() string {
+	if a.Err != nil {
+		return fmt.Sprintf("%v : %v failed (%v)", a.Container, a.Action, a.Err)
+	}
+	return fmt.Sprintf("%v : %v", a.Container, a.Action)
+}
+
+// DeployReport describes the deployment of a pool
+type DeployReport struct {
+	Result     PoolResult       // Outcome of each container of the pool
+	RolledBack bool             // True if the deployment failed and was rolled back
+	Rollback   []RollbackAction // Actions taken to roll back, in order
+}
+
+func (r *DeployReport) String() string {
+	if !r.RolledBack {
+		return fmt.Sprintf("%d containers deployed", len(r.Result))
+	}
+	lines := []string{"Deployment rolled back :"}
+	for _, action := range r.Rollback {
+		lines = append(lines, "- "+action.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replacedContainer is an existing container replaced by a container of a deployed pool
+type replacedContainer struct {
+	container *Container
+	running   bool
+}
+
+// deployment is the state of a deployment, kept to roll it back
+type deployment struct {
+	pool      PoolContainer
+	waves     []PoolContainer
+	snapshots map[string]*docker.Container // Configuration of the containers of the pool before their creation
+	replaced  map[string]replacedContainer // Existing containers, by name of the container replacing them
+	report    *DeployReport
+}
+
+// Deploy runs all containers from the pool, all or nothing, within the limits of the options
+// All images are pulled first : if a pull fails, nothing is changed and the report is not rolled back
+// Existing containers with the same names are then stopped and replaced, and removed once all containers are started
+// A replaced container left by a previous deployment (ex : web_previous) is removed first
+// If a container fails to create or start (or to be healthy with WaitHealthy, checked for every container), every container created is stopped and removed,
+// and the replaced containers are restored. The report describes each rollback action
+// Returns a PoolResult as error if a container failed, or a DependencyCycleError
+// The rollback is done even when the context is done
+func (pool PoolContainer) Deploy(ctx context.Context, forcePull bool, opts PoolOptions) (*DeployReport, error) {
+	waves, err := pool.Waves()
+	if err != nil {
+		return nil, err
+	}
+	d := &deployment{
+		pool:      pool,
+		waves:     waves,
+		snapshots: map[string]*docker.Container{},
+		replaced:  map[string]replacedContainer{},
+		report:    &DeployReport{Result: PoolResult{}},
+	}
+	for _, c := range pool {
+		snapshot, err := c.Clone()
+		if err != nil {
+			return nil, err
+		}
+		d.snapshots[c.Name()] = snapshot.Container
+	}
+
+	puller := newImagePuller(forcePull, opts.PullWorkers)
+	pulled := pool.eachWithOptions(ctx, opts, func(c *Container) error {
+		return puller.pull(ctx, c)
+	})
+	if pulled.Err() != nil {
+		d.report.Result = pulled
+		return d.report, pulled.Err()
+	}
+
+	if err := d.replaceExisting(ctx); err != nil {
+		for _, c := range pool {
+			d.report.Result[c.Name()] = PoolContainerResult{Outcome: PoolFailed, Err: err}
+		}
+		d.rollback()
+		return d.report, d.report.Result.Err()
+	}
+
+	result, err := pool.runInOrder(ctx, puller, opts, true)
+	if err != nil {
+		return nil, err
+	}
+	d.report.Result = result
+	if result.Err() != nil {
+		d.rollback()
+		return d.report, result.Err()
+	}
+
+	// Replaced containers are not needed anymore. They are removed even when the context is done
+	for name, previous := range d.replaced {
+		if err := previous.container.RemoveWithContext(context.Background(), false); err != nil {
+			log.Printf("Can't remove container %v replaced by %v : %v", previous.container.Name(), name, err)
+		}
+	}
+	return d.report, nil
+}
+
+// replaceExisting stops the existing containers with the names of the containers of the pool, and renames them
+func (d *deployment) replaceExisting(ctx context.Context) error {
+	for _, c := range d.pool {
+		name := c.Name()
+		existing, err := c.Client.InspectContainerWithContext(ctx, name)
+		if err != nil {
+			var noSuch *docker.NoSuchContainer
+			if errors.As(err, &noSuch) {
+				continue
+			}
+			return fmt.Errorf("Can't check whether container %v exists because %w", name, err)
+		}
+
+		if err := removeStaleBackup(ctx, c.Client, name+deployBackupSuffix); err != nil {
+			return err
+		}
+
+		replaced := replacedContainer{container: existing, running: existing.IsRunning()}
+		if replaced.running {
+			if err := existing.StopWithContext(ctx); err != nil {
+				return err
+			}
+		}
+		d.replaced[name] = replaced
+		if err := existing.RenameWithContext(ctx, name+deployBackupSuffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeStaleBackup removes a container replaced by a previous deployment, left behind because it could not be removed
+func removeStaleBackup(ctx context.Context, client *Client, name string) error {
+	stale, err := client.InspectContainerWithContext(ctx, name)
+	if err != nil {
+		var noSuch *docker.NoSuchContainer
+		if errors.As(err, &noSuch) {
+			return nil
+		}
+		return fmt.Errorf("Can't check whether container %v exists because %w", name, err)
+	}
+	log.Printf("Removing container %v left by a previous deployment", name)
+	return stale.RemoveWithContext(ctx, false)
+}
+
+// rollback removes the containers created by the deployment and restores the replaced containers
+func (d *deployment) rollback() {
+	ctx := context.Background()
+	d.report.RolledBack = true
+
+	for i := len(d.waves) - 1; i >= 0; i-- {
+		for _, c := range d.waves[i] {
+			name := c.Name()
+			if c.ID() != "" {
+				err := c.RemoveWithContext(ctx, false)
+				d.record(name, "removed new container "+c.ShortID(), err)
+			}
+			c.Container = d.snapshots[name]
+		}
+	}
+
+	for _, wave := range d.waves {
+		for _, c := range wave {
+			name := c.Name()
+			previous, ok := d.replaced[name]
+			if !ok {
+				continue
+			}
+			if previous.container.Name() != name {
+				err := previous.container.RenameWithContext(ctx, name)
+				d.record(name, "renamed previous container "+previous.container.ShortID()+" back", err)
+				if err != nil {
+					continue
+				}
+			}
+			if previous.running {
+				err := previous.container.StartWithContext(ctx)
+				d.record(name, "restarted previous container "+previous.container.ShortID(), err)
+			}
+		}
+	}
+}
+
+// record adds a rollback action to the report, logging it
+func (d *deployment) record(name, action string, err error) {
+	a := RollbackAction{Container: name, Action: action, Err: err}
+	log.Println(a)
+	d.report.Rollback = append(d.report.Rollback, a)
+}
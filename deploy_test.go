@@ -0,0 +1,229 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	dockertest "github.com/fsouza/go-dockerclient/testing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeployReport(t *testing.T) {
+	report := &DeployReport{Result: PoolResult{"web": {Outcome: PoolSucceeded}, "db": {Outcome: PoolSucceeded}}}
+	assert.Equal(t, "2 containers deployed", report.String())
+
+	report = &DeployReport{
+		Result:     PoolResult{"web": {Outcome: PoolFailed, Err: errors.New("boom")}},
+		RolledBack: true,
+		Rollback: []RollbackAction{
+			{Container: "web", Action: "removed new container 0123456789ab"},
+			{Container: "web", Action: "restarted previous container ba9876543210", Err: errors.New("port already allocated")},
+		},
+	}
+	assert.Equal(t, `Deployment rolled back :
+- web : removed new container 0123456789ab
+- web : restarted previous container ba9876543210 failed (port already allocated)`, report.String())
+}
+
+// newDeployServer starts a fake engine with running db and stopped web containers, to be replaced by a deployment
+func newDeployServer(t *testing.T) (*dockertest.DockerServer, *Client, map[string]string) {
+	server, err := dockertest.NewServer("127.0.0.1:0", nil, nil)
+	assert.NoError(t, err)
+	t.Cleanup(server.Stop)
+	engine, err := docker.NewClient(server.URL())
+	assert.NoError(t, err)
+	client := &Client{Docker: engine}
+
+	ids := map[string]string{}
+	for _, name := range []string{"db", "web"} {
+		c, err := client.NewContainer(ContainerOptions{Image: "redis", Name: name})
+		assert.NoError(t, err)
+		assert.NoError(t, c.Run(true))
+		ids[name] = c.ID()
+	}
+	web, err := client.InspectContainerWithContext(context.Background(), "web")
+	assert.NoError(t, err)
+	assert.NoError(t, web.Stop())
+	return server, client, ids
+}
+
+// newDeployPool returns a pool where web depends on db
+func newDeployPool(t *testing.T, client *Client) PoolContainer {
+	db, err := client.NewContainer(ContainerOptions{Image: "redis", Name: "db"})
+	assert.NoError(t, err)
+	web, err := client.NewContainer(ContainerOptions{Image: "redis", Name: "web", DependsOn: []string{"db"}})
+	assert.NoError(t, err)
+	return PoolContainer{db, web}
+}
+
+// assertContainer checks the id and the state of the container with the name
+func assertContainer(t *testing.T, client *Client, name, id string, running bool) {
+	c, err := client.InspectContainerWithContext(context.Background(), name)
+	if assert.NoError(t, err, name) {
+		assert.Equal(t, id, c.ID(), name)
+		assert.Equal(t, running, c.IsRunning(), name)
+	}
+}
+
+func TestDeploy(t *testing.T) {
+	_, client, ids := newDeployServer(t)
+	pool := newDeployPool(t, client)
+
+	report, err := pool.Deploy(context.Background(), false, PoolOptions{})
+	assert.NoError(t, err)
+	assert.False(t, report.RolledBack)
+	assert.Equal(t, []string{}, report.Result.Failed())
+	for _, c := range pool {
+		assert.NotEqual(t, ids[c.Name()], c.ID())
+		assertContainer(t, client, c.Name(), c.ID(), true)
+		_, err := client.InspectContainerWithContext(context.Background(), c.Name()+deployBackupSuffix)
+		assert.Error(t, err, "replaced container is removed")
+		_, err = client.InspectContainerWithContext(context.Background(), ids[c.Name()])
+		assert.Error(t, err, "replaced container is removed")
+	}
+}
+
+func TestDeployRollback(t *testing.T) {
+	server, client, ids := newDeployServer(t)
+	var created []string
+	server.CustomHandler("/containers/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "web" {
+			http.Error(w, "port already allocated", http.StatusInternalServerError)
+			return
+		}
+		server.DefaultHandler().ServeHTTP(w, r)
+		db, err := client.InspectContainerWithContext(context.Background(), "db")
+		assert.NoError(t, err)
+		created = append(created, db.ShortID())
+	}))
+	pool := newDeployPool(t, client)
+
+	report, err := pool.Deploy(context.Background(), false, PoolOptions{})
+	var result PoolResult
+	assert.True(t, errors.As(err, &result))
+	assert.Equal(t, []string{"web"}, result.Failed())
+	assert.True(t, report.RolledBack)
+	assert.Len(t, created, 1)
+	assert.Equal(t, []RollbackAction{
+		{Container: "db", Action: "removed new container " + created[0]},
+		{Container: "db", Action: "renamed previous container " + ids["db"][:12] + " back"},
+		{Container: "db", Action: "restarted previous container " + ids["db"][:12]},
+		{Container: "web", Action: "renamed previous container " + ids["web"][:12] + " back"},
+	}, report.Rollback)
+
+	// The previous containers are back, in their previous state
+	assertContainer(t, client, "db", ids["db"], true)
+	assertContainer(t, client, "web", ids["web"], false)
+	for _, c := range pool {
+		assert.Empty(t, c.ID(), "container of the pool is reset to its configuration")
+	}
+}
+
+func TestDeployRollbackReplaceFailure(t *testing.T) {
+	server, client, ids := newDeployServer(t)
+	server.PrepareFailure("name conflict", "/containers/"+ids["db"]+"/rename")
+	pool := newDeployPool(t, client)
+
+	// db is stopped, but can't be renamed : it is restarted, and web is not replaced
+	report, err := pool.Deploy(context.Background(), false, PoolOptions{})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"db", "web"}, report.Result.Failed())
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, []RollbackAction{
+		{Container: "db", Action: "restarted previous container " + ids["db"][:12]},
+	}, report.Rollback)
+	assertContainer(t, client, "db", ids["db"], true)
+	assertContainer(t, client, "web", ids["web"], false)
+}
+
+func TestDeployRollbackRenameFailure(t *testing.T) {
+	server, client, ids := newDeployServer(t)
+	server.CustomHandler("/containers/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "web" {
+			http.Error(w, "port already allocated", http.StatusInternalServerError)
+			return
+		}
+		server.DefaultHandler().ServeHTTP(w, r)
+	}))
+	// The previous db can't be renamed back, and is left for the operator
+	renames := 0
+	server.CustomHandler("/containers/"+ids["db"]+"/rename", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renames++
+		if renames > 1 {
+			http.Error(w, "name conflict", http.StatusConflict)
+			return
+		}
+		server.DefaultHandler().ServeHTTP(w, r)
+	}))
+	pool := newDeployPool(t, client)
+
+	report, err := pool.Deploy(context.Background(), false, PoolOptions{})
+	assert.Error(t, err)
+	assert.True(t, report.RolledBack)
+	if assert.Len(t, report.Rollback, 3) {
+		renamed := report.Rollback[1]
+		assert.Equal(t, "db", renamed.Container)
+		assert.Error(t, renamed.Err)
+		assert.Equal(t, RollbackAction{Container: "web", Action: "renamed previous container " + ids["web"][:12] + " back"}, report.Rollback[2])
+	}
+	assertContainer(t, client, "db"+deployBackupSuffix, ids["db"], false)
+	assertContainer(t, client, "web", ids["web"], false)
+}
+
+func TestDeployPullsBeforeReplacing(t *testing.T) {
+	server, client, ids := newDeployServer(t)
+	server.PrepareFailure("manifest unknown", "/images/create")
+	pool := newDeployPool(t, client)
+
+	report, err := pool.Deploy(context.Background(), true, PoolOptions{})
+	assert.Error(t, err)
+	assert.False(t, report.RolledBack)
+	assert.Empty(t, report.Rollback)
+	assert.Equal(t, []string{"db", "web"}, report.Result.Failed())
+
+	// Existing containers are untouched
+	assertContainer(t, client, "db", ids["db"], true)
+	assertContainer(t, client, "web", ids["web"], false)
+}
+
+func TestDeployRollbackUnhealthyLeaf(t *testing.T) {
+	server, client, ids := newDeployServer(t)
+	// web has no dependents, and turns unhealthy once started
+	server.CustomHandler("/containers/.*/start", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.DefaultHandler().ServeHTTP(w, r)
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/containers/"), "/start")
+		if c, err := client.InspectContainerWithContext(context.Background(), id); err == nil && c.Name() == "web" {
+			server.MutateContainer(id, docker.State{Running: true, Health: docker.Health{Status: HealthUnhealthy}})
+		}
+	}))
+	pool := newDeployPool(t, client)
+
+	report, err := pool.Deploy(context.Background(), false, PoolOptions{WaitHealthy: true})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"web"}, report.Result.Failed())
+	assert.EqualError(t, report.Result["web"].Err, "Container web is unhealthy")
+	assert.True(t, report.RolledBack)
+	assertContainer(t, client, "db", ids["db"], true)
+	assertContainer(t, client, "web", ids["web"], false)
+}
+
+func TestDeployRemovesStaleBackup(t *testing.T) {
+	_, client, ids := newDeployServer(t)
+	// A previous deployment could not remove the container it replaced
+	stale, err := client.NewContainer(ContainerOptions{Image: "redis", Name: "web" + deployBackupSuffix})
+	assert.NoError(t, err)
+	assert.NoError(t, stale.Create())
+	pool := newDeployPool(t, client)
+
+	_, err = pool.Deploy(context.Background(), false, PoolOptions{})
+	assert.NoError(t, err)
+	for _, id := range []string{stale.ID(), ids["web"]} {
+		_, err = client.InspectContainerWithContext(context.Background(), id)
+		assert.Error(t, err, "replaced containers are removed")
+	}
+	assertContainer(t, client, "web", pool[1].ID(), true)
+}
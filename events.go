@@ -0,0 +1,394 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soprasteria/dockerapi/utils"
+)
+
+// defaultEventsReconnectDelay is the delay before connecting again to the events of the engine after a disconnection
+const defaultEventsReconnectDelay = time.Second
+
+// EventType is the type of object an event of the engine is about
+type EventType string
+
+// Types of events
+const (
+	ContainerEvents EventType = "container"
+	ImageEvents     EventType = "image"
+	NetworkEvents   EventType = "network"
+	VolumeEvents    EventType = "volume"
+)
+
+// Event is an event of the engine
+// Exactly one of Container, Image, Network and Volume is set for events of these types
+type Event struct {
+	Type       EventType         // Type of object the event is about
+	Action     string            // Action on the object (ex : start, die, pull, connect, health_status: healthy)
+	ActorID    string            // ID of the object
+	Attributes map[string]string // Attributes of the object, with labels of containers
+	Time       time.Time         // Time of the event
+
+	Container *ContainerEvent
+	Image     *ImageEvent
+	Network   *NetworkEvent
+	Volume    *VolumeEvent
+}
+
+// ContainerEvent is the detail of an event about a container
+type ContainerEvent struct {
+	ID       string
+	Name     string
+	Image    string
+	ExitCode int               // Exit code of the container, for "die" events
+	Labels   map[string]string // Labels of the container
+}
+
+// ImageEvent is the detail of an event about an image
+type ImageEvent struct {
+	ID   string
+	Name string
+}
+
+// NetworkEvent is the detail of an event about a network
+type NetworkEvent struct {
+	ID          string
+	Name        string
+	Driver      string
+	ContainerID string // Container connected or disconnected
+}
+
+// VolumeEvent is the detail of an event about a volume
+type VolumeEvent struct {
+	Name        string
+	Driver      string
+	ContainerID string // Container mounting or unmounting the volume
+	Destination string // Path of the volume inside the container
+}
+
+// EventFilters selects events. Empty lists select everything
+type EventFilters struct {
+	Types      []EventType // Types of objects
+	Actions    []string    // Actions, without their detail (ex : health_status)
+	Containers []string    // Names or IDs of the containers events are about
+	Labels     []string    // Labels of the objects. Format : key or key=value
+}
+
+// query returns the filters in the format of the engine API
+func (f EventFilters) query() string {
+	filters := map[string][]string{}
+	for _, t := range f.Types {
+		filters["type"] = append(filters["type"], string(t))
+	}
+	if len(f.Actions) > 0 {
+		filters["event"] = f.Actions
+	}
+	if len(f.Containers) > 0 {
+		filters["container"] = f.Containers
+	}
+	if len(f.Labels) > 0 {
+		filters["label"] = f.Labels
+	}
+	res, _ := json.Marshal(filters)
+	return string(res)
+}
+
+// Match checks whether the event is selected by the filters
+func (f EventFilters) Match(e Event) bool {
+	if len(f.Types) > 0 && !containsEventType(f.Types, e.Type) {
+		return false
+	}
+	if len(f.Actions) > 0 {
+		action, _, _ := strings.Cut(e.Action, ":")
+		if !utils.ContainsString(f.Actions, action) {
+			return false
+		}
+	}
+	if len(f.Containers) > 0 {
+		id, name := e.Attributes["container"], ""
+		if e.Container != nil {
+			id, name = e.Container.ID, e.Container.Name
+		}
+		matched := false
+		for _, c := range f.Containers {
+			if c != "" && (c == name || strings.HasPrefix(id, c)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, label := range f.Labels {
+		key, value, hasValue := strings.Cut(label, "=")
+		actual, ok := e.Attributes[key]
+		if !ok || (hasValue && actual != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// engineEvent is an event as sent by the engine
+type engineEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Time     int64 `json:"time"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+// toEvent decodes the typed event
+func (raw engineEvent) toEvent() Event {
+	e := Event{
+		Type:       EventType(raw.Type),
+		Action:     raw.Action,
+		ActorID:    raw.Actor.ID,
+		Attributes: raw.Actor.Attributes,
+		Time:       time.Unix(0, raw.TimeNano),
+	}
+	if raw.TimeNano == 0 {
+		e.Time = time.Unix(raw.Time, 0)
+	}
+	if e.Attributes == nil {
+		e.Attributes = map[string]string{}
+	}
+	attrs := e.Attributes
+
+	switch e.Type {
+	case ContainerEvents:
+		labels := map[string]string{}
+		for key, value := range attrs {
+			if key != "name" && key != "image" && key != "exitCode" && key != "signal" {
+				labels[key] = value
+			}
+		}
+		exitCode, _ := strconv.Atoi(attrs["exitCode"])
+		e.Container = &ContainerEvent{ID: e.ActorID, Name: attrs["name"], Image: attrs["image"], ExitCode: exitCode, Labels: labels}
+	case ImageEvents:
+		e.Image = &ImageEvent{ID: e.ActorID, Name: attrs["name"]}
+	case NetworkEvents:
+		e.Network = &NetworkEvent{ID: e.ActorID, Name: attrs["name"], Driver: attrs["type"], ContainerID: attrs["container"]}
+	case VolumeEvents:
+		e.Volume = &VolumeEvent{Name: e.ActorID, Driver: attrs["driver"], ContainerID: attrs["container"], Destination: attrs["destination"]}
+	}
+	return e
+}
+
+// key identifies the event among events of the same time
+func (raw engineEvent) key() string {
+	return raw.Type + "/" + raw.Action + "/" + raw.Actor.ID
+}
+
+// EventWatcher streams the events of the engine to its subscribers
+// The stream is resumed from the last event received after a disconnection, so that no event is lost
+type EventWatcher struct {
+	client  *Client
+	filters EventFilters
+
+	mu          sync.Mutex
+	subscribers map[*EventSubscription]struct{}
+	stopped     bool
+
+	// Resume point : time of the last event and keys of the events received at that time
+	lastTime int64
+	lastKeys map[string]bool
+}
+
+// EventSubscription receives the events selected by its filters
+type EventSubscription struct {
+	Events  <-chan Event // Closed when the subscription is closed or the watcher stopped
+	events  chan Event
+	done    chan struct{}
+	filters EventFilters
+	watcher *EventWatcher
+
+	mu     sync.Mutex // Held while sending an event
+	closed bool
+	once   sync.Once
+}
+
+// Close stops the subscription
+func (s *EventSubscription) Close() {
+	s.watcher.mu.Lock()
+	delete(s.watcher.subscribers, s)
+	s.watcher.mu.Unlock()
+	s.close()
+}
+
+// close closes the channel of the subscription, once the event being sent, if any, is abandoned
+func (s *EventSubscription) close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		close(s.events)
+	})
+}
+
+// send sends the event to the subscription, waiting for the subscriber to read it
+func (s *EventSubscription) send(ctx context.Context, e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- e:
+	case <-s.done:
+	case <-ctx.Done():
+	}
+}
+
+// WatchEvents starts watching the events of the engine selected by the filters, until the context is done
+// Events are sent to each subscription of the returned watcher. A subscriber not reading its events slows down the others
+func (c *Client) WatchEvents(ctx context.Context, filters EventFilters) *EventWatcher {
+	w := &EventWatcher{
+		client:      c,
+		filters:     filters,
+		subscribers: map[*EventSubscription]struct{}{},
+		lastKeys:    map[string]bool{},
+	}
+	go w.run(ctx)
+	return w
+}
+
+// Subscribe creates a subscription to the events of the watcher selected by the filters
+func (w *EventWatcher) Subscribe(filters EventFilters) *EventSubscription {
+	events := make(chan Event, 100)
+	s := &EventSubscription{Events: events, events: events, done: make(chan struct{}), filters: filters, watcher: w}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		s.close()
+		return s
+	}
+	w.subscribers[s] = struct{}{}
+	return s
+}
+
+// run streams the events, connecting again after each disconnection, until the context is done
+func (w *EventWatcher) run(ctx context.Context) {
+	defer w.stop()
+	connected := time.Now()
+	for {
+		err := w.stream(ctx, connected)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Events of the engine interrupted, connecting again : %v", err)
+		select {
+		case <-time.After(defaultEventsReconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// stream reads the events of the engine until the end of the stream
+// The stream is resumed from the last event received, or from the first connection if none
+func (w *EventWatcher) stream(ctx context.Context, connected time.Time) error {
+	query := url.Values{}
+	query.Set("filters", w.filters.query())
+	if w.lastTime != 0 {
+		query.Set("since", timeParam(time.Unix(0, w.lastTime)))
+	} else {
+		query.Set("since", timeParam(connected))
+	}
+	resp, err := w.client.request(ctx, http.MethodGet, "/events", query, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var raw engineEvent
+		if err := decoder.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("Stream closed by the engine")
+			}
+			return err
+		}
+		if !w.resume(raw) {
+			continue
+		}
+		w.publish(ctx, raw.toEvent())
+	}
+}
+
+// resume records the event as the resume point
+// Returns false if the event was already received before a disconnection
+func (w *EventWatcher) resume(raw engineEvent) bool {
+	eventTime := raw.TimeNano
+	if eventTime == 0 {
+		eventTime = raw.Time * int64(time.Second)
+	}
+	switch {
+	case eventTime < w.lastTime:
+		return false
+	case eventTime == w.lastTime:
+		if w.lastKeys[raw.key()] {
+			return false
+		}
+	default:
+		w.lastTime = eventTime
+		w.lastKeys = map[string]bool{}
+	}
+	w.lastKeys[raw.key()] = true
+	return true
+}
+
+// publish sends the event to the matching subscriptions
+func (w *EventWatcher) publish(ctx context.Context, e Event) {
+	for _, s := range w.subscriptions() {
+		if s.filters.Match(e) {
+			s.send(ctx, e)
+		}
+	}
+}
+
+// subscriptions returns the current subscriptions
+func (w *EventWatcher) subscriptions() []*EventSubscription {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	res := []*EventSubscription{}
+	for s := range w.subscribers {
+		res = append(res, s)
+	}
+	return res
+}
+
+// stop closes all subscriptions
+func (w *EventWatcher) stop() {
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+	for _, s := range w.subscriptions() {
+		s.Close()
+	}
+}
+
+func containsEventType(types []EventType, t EventType) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeEngineEvent(t *testing.T, message string) engineEvent {
+	var raw engineEvent
+	assert.NoError(t, json.Unmarshal([]byte(message), &raw))
+	return raw
+}
+
+func TestEngineEventToEvent(t *testing.T) {
+	raw := decodeEngineEvent(t, `{"Type":"container","Action":"die","Actor":{"ID":"abc123","Attributes":{"name":"web","image":"nginx","exitCode":"137","app":"front"}},"time":1496312430,"timeNano":1496312430000000042}`)
+	e := raw.toEvent()
+	assert.Equal(t, ContainerEvents, e.Type)
+	assert.Equal(t, "die", e.Action)
+	assert.Equal(t, time.Unix(1496312430, 42), e.Time)
+	assert.Equal(t, &ContainerEvent{ID: "abc123", Name: "web", Image: "nginx", ExitCode: 137, Labels: map[string]string{"app": "front"}}, e.Container)
+	assert.Nil(t, e.Network)
+
+	raw = decodeEngineEvent(t, `{"Type":"network","Action":"connect","Actor":{"ID":"net1","Attributes":{"name":"backend","type":"bridge","container":"abc123"}},"time":1496312430}`)
+	e = raw.toEvent()
+	assert.Equal(t, &NetworkEvent{ID: "net1", Name: "backend", Driver: "bridge", ContainerID: "abc123"}, e.Network)
+	assert.Equal(t, time.Unix(1496312430, 0), e.Time)
+
+	raw = decodeEngineEvent(t, `{"Type":"volume","Action":"mount","Actor":{"ID":"data","Attributes":{"driver":"local","container":"abc123","destination":"/data"}}}`)
+	assert.Equal(t, &VolumeEvent{Name: "data", Driver: "local", ContainerID: "abc123", Destination: "/data"}, raw.toEvent().Volume)
+
+	raw = decodeEngineEvent(t, `{"Type":"image","Action":"pull","Actor":{"ID":"redis:latest","Attributes":{"name":"redis"}}}`)
+	assert.Equal(t, &ImageEvent{ID: "redis:latest", Name: "redis"}, raw.toEvent().Image)
+}
+
+func TestEventFiltersMatch(t *testing.T) {
+	e := decodeEngineEvent(t, `{"Type":"container","Action":"health_status: healthy","Actor":{"ID":"abc123","Attributes":{"name":"web","env":"prod"}}}`).toEvent()
+	assert.True(t, EventFilters{}.Match(e))
+	assert.True(t, EventFilters{Types: []EventType{NetworkEvents, ContainerEvents}}.Match(e))
+	assert.False(t, EventFilters{Types: []EventType{ImageEvents}}.Match(e))
+	assert.True(t, EventFilters{Actions: []string{"health_status"}}.Match(e))
+	assert.False(t, EventFilters{Actions: []string{"die"}}.Match(e))
+	assert.True(t, EventFilters{Containers: []string{"web"}}.Match(e))
+	assert.True(t, EventFilters{Containers: []string{"abc"}}.Match(e))
+	assert.False(t, EventFilters{Containers: []string{"db"}}.Match(e))
+	assert.True(t, EventFilters{Labels: []string{"env", "env=prod"}}.Match(e))
+	assert.False(t, EventFilters{Labels: []string{"env=dev"}}.Match(e))
+	assert.False(t, EventFilters{Labels: []string{"team"}}.Match(e))
+
+	connect := decodeEngineEvent(t, `{"Type":"network","Action":"connect","Actor":{"ID":"net1","Attributes":{"container":"abc123"}}}`).toEvent()
+	assert.True(t, EventFilters{Containers: []string{"abc123"}}.Match(connect))
+}
+
+func TestEventFiltersQuery(t *testing.T) {
+	f := EventFilters{Types: []EventType{ContainerEvents}, Actions: []string{"die"}, Labels: []string{"env=prod"}}
+	assert.JSONEq(t, `{"type":["container"],"event":["die"],"label":["env=prod"]}`, f.query())
+	assert.Equal(t, `{}`, EventFilters{}.query())
+}
+
+func TestEventWatcherResume(t *testing.T) {
+	w := &EventWatcher{lastKeys: map[string]bool{}}
+	start := decodeEngineEvent(t, `{"Type":"container","Action":"start","Actor":{"ID":"a"},"timeNano":10}`)
+	stop := decodeEngineEvent(t, `{"Type":"container","Action":"stop","Actor":{"ID":"a"},"timeNano":10}`)
+	older := decodeEngineEvent(t, `{"Type":"container","Action":"create","Actor":{"ID":"a"},"timeNano":5}`)
+	newer := decodeEngineEvent(t, `{"Type":"container","Action":"die","Actor":{"ID":"a"},"timeNano":20}`)
+
+	assert.True(t, w.resume(start))
+	assert.True(t, w.resume(stop))
+	// Events sent again after a reconnection
+	assert.False(t, w.resume(start))
+	assert.False(t, w.resume(older))
+	assert.True(t, w.resume(newer))
+	assert.Equal(t, int64(20), w.lastTime)
+}
+
+func TestEventWatcherSubscriptions(t *testing.T) {
+	w := &EventWatcher{subscribers: map[*EventSubscription]struct{}{}, lastKeys: map[string]bool{}}
+	all := w.Subscribe(EventFilters{})
+	dies := w.Subscribe(EventFilters{Actions: []string{"die"}})
+
+	ctx := context.Background()
+	w.publish(ctx, Event{Type: ContainerEvents, Action: "start"})
+	w.publish(ctx, Event{Type: ContainerEvents, Action: "die"})
+	assert.Equal(t, "start", (<-all.Events).Action)
+	assert.Equal(t, "die", (<-all.Events).Action)
+	assert.Equal(t, "die", (<-dies.Events).Action)
+
+	dies.Close()
+	_, ok := <-dies.Events
+	assert.False(t, ok)
+	w.publish(ctx, Event{Type: ContainerEvents, Action: "die"})
+	assert.Equal(t, "die", (<-all.Events).Action)
+
+	w.stop()
+	_, ok = <-all.Events
+	assert.False(t, ok)
+	_, ok = <-w.Subscribe(EventFilters{}).Events
+	assert.False(t, ok)
+}
@@ -0,0 +1,408 @@
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// defaultExecStartTimeout is the maximum time to wait for the engine to attach to a command run by Exec
+const defaultExecStartTimeout = 30 * time.Second
+
+// TerminalSize is the size of a TTY, in characters
+type TerminalSize struct {
+	Height uint
+	Width  uint
+}
+
+// ExecStreamType identifies the output of a command a line comes from
+type ExecStreamType string
+
+// Outputs of a command
+const (
+	ExecStdout ExecStreamType = "stdout"
+	ExecStderr ExecStreamType = "stderr"
+)
+
+// ExecLine is a line written by a command, without its line ending
+type ExecLine struct {
+	Stream ExecStreamType
+	Line   string
+}
+
+// ExecOptions defines options for a command executed inside a container
+// Outputs are captured in ExecResult unless they are sent to writers or to a line function
+type ExecOptions struct {
+	Cmd           []string            // Command to execute
+	Env           []string            // Environment variables to set for the command. Format : key=value
+	User          string              // User running the command. Format : user, user:group, uid or uid:gid
+	WorkingDir    string              // Working directory of the command
+	Privileged    bool                // Gives extended privileges to the command
+	Tty           bool                // Allocates a TTY. Stderr is merged into stdout by the TTY
+	Stdin         io.Reader           // Fed to the command if not nil. Closed at the end of the command if it is an io.Closer
+	Stdout        io.Writer           // Receives the output of the command
+	Stderr        io.Writer           // Receives the error output of the command
+	OnLine        func(ExecLine)      // Receives each line of the outputs as soon as it is written
+	MaxLineLength int                 // Longer lines are split in several lines. 1MB if 0
+	MaxCapture    int64               // Maximum number of bytes captured in ExecResult, for both outputs. No limit if 0
+	TailLines     int                 // Number of last lines kept in ExecResult, for error reports
+	Resize        <-chan TerminalSize // New sizes of the TTY, to send when the terminal of the caller is resized
+	StartTimeout  time.Duration       // Maximum time to wait for the engine to attach to the command. No limit if 0
+}
+
+// ExecResult is the result of a command executed inside a container
+type ExecResult struct {
+	ExitCode  int        // Exit code of the command
+	Stdout    string     // Captured output of the command
+	Stderr    string     // Captured error output of the command
+	Truncated bool       // True if captured outputs were truncated to ExecOptions.MaxCapture
+	Tail      []ExecLine // Last lines of the outputs, up to ExecOptions.TailLines
+}
+
+// ExecWithOptions executes a command on a container
+// A non-zero exit code is not an error, it is returned in the result
+// The execution is aborted when the context is done
+func (c *Container) ExecWithOptions(ctx context.Context, opts ExecOptions) (ExecResult, error) {
+	return execWithOptions(ctx, c, c.Client, opts)
+}
+
+func execWithOptions(ctx context.Context, c SimpleContainer, client *Client, opts ExecOptions) (ExecResult, error) {
+	res := ExecResult{}
+	if c.ID() == "" {
+		return res, fmt.Errorf("Container %+v does not exist", c)
+	}
+	if len(opts.Cmd) == 0 {
+		return res, errors.New("Cmd is required")
+	}
+	command := strings.Join(opts.Cmd, " ")
+
+	output := newExecOutput(opts)
+	defer output.close()
+
+	exec, err := client.Docker.CreateExec(docker.CreateExecOptions{
+		AttachStdin:  opts.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          opts.Tty,
+		Cmd:          opts.Cmd,
+		Env:          opts.Env,
+		User:         opts.User,
+		WorkingDir:   opts.WorkingDir,
+		Privileged:   opts.Privileged,
+		Container:    c.ID(),
+		Context:      ctx,
+	})
+	if err != nil {
+		return res, fmt.Errorf("Can't create command %q on container %v : %v", command, c.ShortID(), err)
+	}
+
+	success := make(chan struct{})
+	session, err := client.Docker.StartExecNonBlocking(exec.ID, docker.StartExecOptions{
+		InputStream:  opts.Stdin,
+		OutputStream: output.stdout,
+		ErrorStream:  output.stderr,
+		Tty:          opts.Tty,
+		RawTerminal:  opts.Tty,
+		Success:      success,
+		Context:      ctx,
+	})
+	if err != nil {
+		return res, fmt.Errorf("Can't start command %q on container %v : %v", command, c.ShortID(), err)
+	}
+
+	// The engine client sends on success once attached, then waits for an acknowledgement
+	var startTimeout <-chan time.Time
+	if opts.StartTimeout > 0 {
+		timer := time.NewTimer(opts.StartTimeout)
+		defer timer.Stop()
+		startTimeout = timer.C
+	}
+	abort := func() {
+		// Acknowledges the attachment later on, so that the engine client doesn't leak
+		go func() {
+			<-success
+			close(success)
+			session.Close()
+		}()
+	}
+	select {
+	case <-success:
+		close(success)
+	case <-startTimeout:
+		abort()
+		return res, fmt.Errorf("Command %q not started on container %v after %v", command, c.ShortID(), opts.StartTimeout)
+	case <-ctx.Done():
+		abort()
+		return res, ctx.Err()
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	if opts.Resize != nil {
+		go resizeExec(client, exec.ID, opts.Resize, done)
+	}
+
+	finished := make(chan error, 1)
+	go func() {
+		finished <- session.Wait()
+	}()
+	select {
+	case err = <-finished:
+	case <-ctx.Done():
+		session.Close()
+		return res, ctx.Err()
+	}
+	if err != nil {
+		return res, fmt.Errorf("Command %q failed on container %v : %v", command, c.ShortID(), err)
+	}
+
+	execInspect, err := client.Docker.InspectExec(exec.ID)
+	if err != nil {
+		return res, err
+	}
+	output.close()
+	res = output.result()
+	res.ExitCode = execInspect.ExitCode
+	return res, nil
+}
+
+// resizeExec resizes the TTY of the exec each time a new size is received, until done is closed
+func resizeExec(client *Client, id string, sizes <-chan TerminalSize, done <-chan struct{}) {
+	for {
+		select {
+		case size, ok := <-sizes:
+			if !ok {
+				return
+			}
+			if err := client.Docker.ResizeExecTTY(id, int(size.Height), int(size.Width)); err != nil {
+				log.Printf("Can't resize TTY of exec %v : %v", id, err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// execOutput dispatches the outputs of a command to the writers, line function, captures and tail requested by its options
+// Writes of the engine client are serialized, and refused once closed, as the engine client may still write after an aborted command returned
+type execOutput struct {
+	opts           ExecOptions
+	stdout, stderr io.Writer // Writers given to the engine client
+
+	mu             sync.Mutex
+	closed         bool
+	lines          []*lineWriter
+	capturedStdout bytes.Buffer
+	capturedStderr bytes.Buffer
+	captured       int64
+	truncated      bool
+	tail           []ExecLine
+}
+
+func newExecOutput(opts ExecOptions) *execOutput {
+	o := &execOutput{opts: opts}
+	o.stdout = o.stream(ExecStdout, opts.Stdout, &o.capturedStdout)
+	o.stderr = o.stream(ExecStderr, opts.Stderr, &o.capturedStderr)
+	return o
+}
+
+// stream builds the writer of an output of the command
+func (o *execOutput) stream(stream ExecStreamType, w io.Writer, capture *bytes.Buffer) io.Writer {
+	writers := []io.Writer{}
+	if w != nil {
+		writers = append(writers, w)
+	} else if o.opts.OnLine == nil {
+		writers = append(writers, execCapture{output: o, buf: capture})
+	}
+	if o.opts.OnLine != nil || o.opts.TailLines > 0 {
+		lines := newLineWriter(func(line string) {
+			o.line(ExecLine{Stream: stream, Line: line})
+		})
+		if o.opts.MaxLineLength > 0 {
+			lines.maxLength = o.opts.MaxLineLength
+		}
+		o.lines = append(o.lines, lines)
+		writers = append(writers, lines)
+	}
+	return execOutputWriter{output: o, w: io.MultiWriter(writers...)}
+}
+
+// line keeps the line in the tail and sends it to the line function
+func (o *execOutput) line(l ExecLine) {
+	if o.opts.TailLines > 0 {
+		o.tail = append(o.tail, l)
+		if len(o.tail) > o.opts.TailLines {
+			o.tail = o.tail[len(o.tail)-o.opts.TailLines:]
+		}
+	}
+	if o.opts.OnLine != nil {
+		o.opts.OnLine(l)
+	}
+}
+
+// close refuses any further write and sends the last lines without line ending
+func (o *execOutput) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return
+	}
+	o.closed = true
+	for _, lines := range o.lines {
+		lines.Flush()
+	}
+}
+
+// result returns the captured outputs and tail, once closed
+func (o *execOutput) result() ExecResult {
+	return ExecResult{
+		Stdout:    o.capturedStdout.String(),
+		Stderr:    o.capturedStderr.String(),
+		Truncated: o.truncated,
+		Tail:      o.tail,
+	}
+}
+
+type execOutputWriter struct {
+	output *execOutput
+	w      io.Writer
+}
+
+func (w execOutputWriter) Write(p []byte) (int, error) {
+	w.output.mu.Lock()
+	defer w.output.mu.Unlock()
+	if w.output.closed {
+		return 0, io.ErrClosedPipe
+	}
+	return w.w.Write(p)
+}
+
+// execCapture captures an output of the command in a buffer, within the capture limit shared by both outputs
+type execCapture struct {
+	output *execOutput
+	buf    *bytes.Buffer
+}
+
+func (c execCapture) Write(p []byte) (int, error) {
+	n := len(p)
+	if max := c.output.opts.MaxCapture; max > 0 && c.output.captured+int64(n) > max {
+		p = p[:max-c.output.captured]
+		c.output.truncated = true
+	}
+	c.output.captured += int64(len(p))
+	c.buf.Write(p)
+	// Dropped bytes are reported as written, the command must not fail because of the capture limit
+	return n, nil
+}
+
+// defaultMaxLineLength is the length above which lines are split, when no other length is given
+const defaultMaxLineLength = 1024 * 1024
+
+// lineWriter is a writer calling a function for each line written, without the line ending
+// Lines longer than maxLength are split, so that an output without line endings (ex : binary data) is not held in memory
+type lineWriter struct {
+	onLine    func(line string)
+	maxLength int
+	partial   []byte
+}
+
+func newLineWriter(onLine func(line string)) *lineWriter {
+	return &lineWriter{onLine: onLine, maxLength: defaultMaxLineLength}
+}
+
+// Write sends each complete line to the line function and keeps the last partial line for the next writes
+// Only the bytes written are scanned, up to the maximum length of the line
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for {
+		room := w.maxLength - len(w.partial)
+		limit := len(p)
+		if limit > room {
+			limit = room + 1
+		}
+		i := bytes.IndexByte(p[:limit], '\n')
+		if i == -1 && len(p) > room {
+			w.onLine(string(append(w.partial, p[:room]...)))
+			w.partial = w.partial[:0]
+			p = p[room:]
+			continue
+		}
+		if i == -1 {
+			w.partial = append(w.partial, p...)
+			return n, nil
+		}
+		w.onLine(strings.TrimSuffix(string(append(w.partial, p[:i]...)), "\r"))
+		w.partial = w.partial[:0]
+		p = p[i+1:]
+	}
+}
+
+// Flush sends the last line if it has no line ending
+func (w *lineWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.onLine(strings.TrimSuffix(string(w.partial), "\r"))
+		w.partial = w.partial[:0]
+	}
+}
+
+// ExecStream executes a command on a container, sending each line of its output and error output to onLine as soon as it is written
+// Returns the exit code of the command
+// The execution is aborted when the context is done
+func (c *Container) ExecStream(ctx context.Context, cmd []string, onLine func(line string)) (int, error) {
+	return execStream(ctx, c, c.Client, cmd, onLine)
+}
+
+func execStream(ctx context.Context, c SimpleContainer, client *Client, cmd []string, onLine func(line string)) (int, error) {
+	res, err := execWithOptions(ctx, c, client, ExecOptions{
+		Cmd: cmd,
+		OnLine: func(l ExecLine) {
+			onLine(l.Line)
+		},
+		StartTimeout: defaultExecStartTimeout,
+	})
+	return res.ExitCode, err
+}
+
+func exec(ctx context.Context, c SimpleContainer, client *Client, cmd []string) (logs []string, err error) {
+	exitCode, err := execStream(ctx, c, client, cmd, func(line string) {
+		logs = append(logs, line)
+	})
+	if err != nil {
+		return logs, err
+	}
+	if exitCode != 0 {
+		return logs, fmt.Errorf("Command %q failed : %v ", strings.Join(cmd, " "), exitCode)
+	}
+	return logs, nil
+}
+
+// ExecSh executes a command in sh shell on a container
+func (c *Container) ExecSh(cmd []string) (logs []string, err error) {
+	return c.ExecShWithContext(context.Background(), cmd)
+}
+
+// ExecShWithContext executes a command in sh shell on a container
+// The execution is aborted when the context is done
+func (c *Container) ExecShWithContext(ctx context.Context, cmd []string) (logs []string, err error) {
+	shell := []string{"/bin/sh", "-c"}
+	return c.ExecWithContext(ctx, append(shell, cmd...))
+}
+
+// Exec executes a command on a container
+func (c *Container) Exec(cmd []string) (logs []string, err error) {
+	return c.ExecWithContext(context.Background(), cmd)
+}
+
+// ExecWithContext executes a command on a container
+// The execution is aborted when the context is done
+func (c *Container) ExecWithContext(ctx context.Context, cmd []string) (logs []string, err error) {
+	return exec(ctx, c, c.Client, cmd)
+}
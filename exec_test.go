@@ -0,0 +1,233 @@
+package dockerapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriter(t *testing.T) {
+	lines := []string{}
+	w := newLineWriter(func(line string) {
+		lines = append(lines, line)
+	})
+	w.Write([]byte("first"))
+	assert.Empty(t, lines)
+	w.Write([]byte(" line\nsecond line\r\n\nthi"))
+	assert.Equal(t, []string{"first line", "second line", ""}, lines)
+	w.Write([]byte("rd"))
+	w.Flush()
+	assert.Equal(t, []string{"first line", "second line", "", "third"}, lines)
+	w.Flush()
+	assert.Len(t, lines, 4)
+}
+
+func TestLineWriterMaxLength(t *testing.T) {
+	lines := []string{}
+	w := newLineWriter(func(line string) {
+		lines = append(lines, line)
+	})
+	w.maxLength = 4
+	w.Write([]byte("abcd\nabcdefghij"))
+	assert.Equal(t, []string{"abcd", "abcd", "efgh"}, lines)
+	w.Write([]byte("\n"))
+	assert.Equal(t, []string{"abcd", "abcd", "efgh", "ij"}, lines)
+}
+
+func TestExecOutput(t *testing.T) {
+	lines := []ExecLine{}
+	var stdout bytes.Buffer
+	output := newExecOutput(ExecOptions{
+		Stdout: &stdout,
+		OnLine: func(l ExecLine) {
+			lines = append(lines, l)
+		},
+		TailLines: 2,
+	})
+	output.stdout.Write([]byte("out 1\nout 2\n"))
+	output.stderr.Write([]byte("err 1\nerr"))
+	output.close()
+	_, err := output.stdout.Write([]byte("after close\n"))
+	assert.Error(t, err)
+
+	res := output.result()
+	assert.Equal(t, "out 1\nout 2\n", stdout.String())
+	assert.Equal(t, []ExecLine{
+		{ExecStdout, "out 1"},
+		{ExecStdout, "out 2"},
+		{ExecStderr, "err 1"},
+		{ExecStderr, "err"},
+	}, lines)
+	assert.Equal(t, []ExecLine{{ExecStderr, "err 1"}, {ExecStderr, "err"}}, res.Tail)
+	// Outputs sent to writers or to the line function are not captured
+	assert.Empty(t, res.Stdout)
+	assert.Empty(t, res.Stderr)
+}
+
+func TestExecOutputCapture(t *testing.T) {
+	output := newExecOutput(ExecOptions{MaxCapture: 8})
+	output.stdout.Write([]byte("12345"))
+	n, err := output.stderr.Write([]byte("67890"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	output.stdout.Write([]byte("abc"))
+	output.close()
+
+	res := output.result()
+	assert.Equal(t, "12345", res.Stdout)
+	assert.Equal(t, "678", res.Stderr)
+	assert.True(t, res.Truncated)
+	assert.Empty(t, res.Tail)
+}
+
+// fakeExecEngine answers the exec requests of a command reading its stdin, then writing it to stdout and a message to stderr
+// The create request and the TTY sizes are recorded
+type fakeExecEngine struct {
+	created docker.CreateExecOptions
+	started struct{ Tty, RawTerminal bool }
+	resized chan string
+}
+
+func (e *fakeExecEngine) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/version":
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/0123456789ab/exec":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&e.created))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"exec1"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/exec/exec1/resize":
+			e.resized <- r.URL.Query().Get("h") + "x" + r.URL.Query().Get("w")
+		case r.Method == http.MethodPost && r.URL.Path == "/exec/exec1/start":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&e.started))
+			conn, buf := hijackExec(t, w)
+			if conn == nil {
+				return
+			}
+			defer conn.Close()
+			stdin, _ := io.ReadAll(buf)
+			if e.created.Tty {
+				select {
+				case size := <-e.resized:
+					buf.WriteString("resized " + size + "\r\n")
+				case <-time.After(5 * time.Second):
+					t.Error("TTY not resized")
+				}
+				buf.WriteString("got " + string(stdin) + "\r\n")
+			} else {
+				writeFrame(buf, 1, "got "+string(stdin)+"\n")
+				writeFrame(buf, 2, "warning\n")
+			}
+			buf.Flush()
+		case r.Method == http.MethodGet && r.URL.Path == "/exec/exec1/json":
+			w.Write([]byte(`{"ID":"exec1","Running":false,"ExitCode":3}`))
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	}
+}
+
+// hijackExec upgrades the connection of an exec start request to a raw stream, as the engine does
+// Returns a nil connection if it can't be hijacked
+func hijackExec(t *testing.T, w http.ResponseWriter) (net.Conn, *bufio.ReadWriter) {
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if !assert.NoError(t, err) {
+		return nil, nil
+	}
+	buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	buf.Flush()
+	return conn, buf
+}
+
+// writeFrame writes a frame of a multiplexed stream of the engine
+func writeFrame(w io.Writer, stream byte, payload string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	w.Write(header)
+	w.Write([]byte(payload))
+}
+
+func TestExecWithOptions(t *testing.T) {
+	engine := &fakeExecEngine{resized: make(chan string, 1)}
+	client := newTestClient(t, engine.handle(t))
+	c := &Container{Container: &docker.Container{ID: "0123456789ab", Name: "/db"}, Client: client}
+
+	var stderr bytes.Buffer
+	res, err := c.ExecWithOptions(context.Background(), ExecOptions{
+		Cmd:        []string{"psql", "-f", "-"},
+		Env:        []string{"PGUSER=app"},
+		User:       "postgres",
+		WorkingDir: "/migrations",
+		Privileged: true,
+		Stdin:      strings.NewReader("select 1;"),
+		Stderr:     &stderr,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, docker.CreateExecOptions{
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          []string{"psql", "-f", "-"},
+		Env:          []string{"PGUSER=app"},
+		User:         "postgres",
+		WorkingDir:   "/migrations",
+		Privileged:   true,
+		Container:    "0123456789ab",
+	}, engine.created)
+	assert.False(t, engine.started.Tty)
+	assert.Equal(t, 3, res.ExitCode, "exit code is the one of the inspected exec")
+	assert.Equal(t, "got select 1;\n", res.Stdout)
+	assert.Empty(t, res.Stderr, "error output is sent to the writer")
+	assert.Equal(t, "warning\n", stderr.String())
+}
+
+func TestExecWithOptionsTty(t *testing.T) {
+	engine := &fakeExecEngine{resized: make(chan string, 1)}
+	client := newTestClient(t, engine.handle(t))
+	c := &Container{Container: &docker.Container{ID: "0123456789ab", Name: "/db"}, Client: client}
+
+	resize := make(chan TerminalSize, 1)
+	resize <- TerminalSize{Height: 24, Width: 80}
+	res, err := c.ExecWithOptions(context.Background(), ExecOptions{
+		Cmd:    []string{"sh"},
+		Tty:    true,
+		Stdin:  strings.NewReader("exit"),
+		Resize: resize,
+	})
+	assert.NoError(t, err)
+	assert.True(t, engine.created.Tty)
+	assert.Equal(t, struct{ Tty, RawTerminal bool }{true, true}, engine.started)
+	// The output of a TTY is not multiplexed
+	assert.Equal(t, "resized 24x80\r\ngot exit\r\n", res.Stdout)
+	assert.Empty(t, res.Stderr)
+	assert.Equal(t, 3, res.ExitCode)
+}
+
+func TestLineWriterDefaultMaxLength(t *testing.T) {
+	lengths := []int{}
+	w := newLineWriter(func(line string) {
+		lengths = append(lengths, len(line))
+	})
+	// Binary data without line ending is not kept in memory
+	chunk := bytes.Repeat([]byte{0xff}, 256*1024)
+	for i := 0; i < 9; i++ {
+		w.Write(chunk)
+	}
+	assert.Equal(t, []int{defaultMaxLineLength, defaultMaxLineLength}, lengths)
+	assert.Len(t, w.partial, 256*1024)
+	w.Flush()
+	assert.Equal(t, []int{defaultMaxLineLength, defaultMaxLineLength, 256 * 1024}, lengths)
+}
@@ -0,0 +1,137 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// defaultHealthPollInterval is the delay between two checks of the health of a container
+const defaultHealthPollInterval = 500 * time.Millisecond
+
+// minHealthDuration is the minimum duration accepted by the engine for the durations of healthchecks
+const minHealthDuration = time.Millisecond
+
+// Health statuses of containers
+const (
+	HealthNone      = "none"      // Container has no healthcheck
+	HealthStarting  = "starting"  // Container is in its start period, or was not checked yet
+	HealthHealthy   = "healthy"   // Last checks succeeded
+	HealthUnhealthy = "unhealthy" // Checks failed Retries times in a row
+)
+
+// Healthcheck defines how the engine checks that a container is healthy. Zero values inherit the healthcheck of the image
+type Healthcheck struct {
+	Test        []string      // Command checking the container. Format : ["CMD", "executable", "arg"], ["CMD-SHELL", "command"] or ["NONE"] to disable the healthcheck of the image
+	Interval    time.Duration // Time between two checks
+	Timeout     time.Duration // Time after which a check is considered failed
+	Retries     int           // Number of consecutive failed checks before the container is unhealthy
+	StartPeriod time.Duration // Time for the container to initialize, during which failed checks are not counted
+}
+
+// healthConfig validates the healthcheck and converts it to the engine format
+func (h *Healthcheck) healthConfig() (*docker.HealthConfig, error) {
+	if h == nil {
+		return nil, nil
+	}
+	if len(h.Test) > 0 {
+		switch h.Test[0] {
+		case "NONE":
+			if len(h.Test) > 1 {
+				return nil, errors.New("Healthcheck test NONE takes no argument")
+			}
+		case "CMD", "CMD-SHELL":
+			if len(h.Test) < 2 {
+				return nil, fmt.Errorf("Healthcheck test %v requires a command", h.Test[0])
+			}
+		default:
+			return nil, fmt.Errorf("Healthcheck test must start with CMD, CMD-SHELL or NONE, not %q", h.Test[0])
+		}
+	}
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{{"interval", h.Interval}, {"timeout", h.Timeout}, {"start period", h.StartPeriod}}
+	for _, d := range durations {
+		if d.value < 0 || (d.value > 0 && d.value < minHealthDuration) {
+			return nil, fmt.Errorf("Healthcheck %v must be 0 or at least %v, got %v", d.name, minHealthDuration, d.value)
+		}
+	}
+	if h.Retries < 0 {
+		return nil, fmt.Errorf("Healthcheck retries can't be negative, got %v", h.Retries)
+	}
+	return &docker.HealthConfig{
+		Test:        h.Test,
+		Interval:    h.Interval,
+		Timeout:     h.Timeout,
+		Retries:     h.Retries,
+		StartPeriod: h.StartPeriod,
+	}, nil
+}
+
+// HealthProbe is the result of a check of the health of a container
+type HealthProbe struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int    // 0 if the check succeeded
+	Output   string // Output of the check command, truncated by the engine
+}
+
+// HealthResult is the health of a container
+type HealthResult struct {
+	Status        string        // Health status (ex : HealthHealthy)
+	FailingStreak int           // Number of consecutive failed checks
+	Probes        []HealthProbe // Last checks, oldest first
+}
+
+// Health returns the health of the container, as of its last refresh
+func (c *Container) Health() HealthResult {
+	if c.Container == nil {
+		return HealthResult{Status: HealthNone}
+	}
+	health := c.Container.State.Health
+	res := HealthResult{Status: health.Status, FailingStreak: health.FailingStreak}
+	if res.Status == "" {
+		res.Status = HealthNone
+	}
+	for _, check := range health.Log {
+		res.Probes = append(res.Probes, HealthProbe{Start: check.Start, End: check.End, ExitCode: check.ExitCode, Output: check.Output})
+	}
+	return res
+}
+
+// WaitHealthy waits until the container is healthy, then returns its health with the last checks
+// Returns immediately with HealthNone if the container has no healthcheck
+// Returns error if the container is unhealthy or stops, or if the context is done
+func (c *Container) WaitHealthy(ctx context.Context) (HealthResult, error) {
+	for {
+		if err := c.RefreshWithContext(ctx); err != nil {
+			return HealthResult{}, fmt.Errorf("Can't check health of container %v because %w", c.Name(), err)
+		}
+		health := c.Health()
+		switch {
+		case !c.Container.State.Running:
+			return health, fmt.Errorf("Container %v stopped before being healthy", c.Name())
+		case health.Status == HealthNone || health.Status == HealthHealthy:
+			return health, nil
+		case health.Status == HealthUnhealthy:
+			return health, fmt.Errorf("Container %v is unhealthy%v", c.Name(), health.lastOutput())
+		}
+		select {
+		case <-time.After(defaultHealthPollInterval):
+		case <-ctx.Done():
+			return health, fmt.Errorf("Can't wait for container %v to be healthy because %w", c.Name(), ctx.Err())
+		}
+	}
+}
+
+// lastOutput returns the output of the last check, to add to error messages
+func (h HealthResult) lastOutput() string {
+	if len(h.Probes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" : %q", h.Probes[len(h.Probes)-1].Output)
+}
@@ -0,0 +1,43 @@
+package dockerapi
+
+import (
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthConfig(t *testing.T) {
+	config, err := (*Healthcheck)(nil).healthConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	config, err = (&Healthcheck{Test: []string{"CMD-SHELL", "curl -f localhost"}, Interval: 5 * time.Second, Retries: 3}).healthConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, &docker.HealthConfig{Test: []string{"CMD-SHELL", "curl -f localhost"}, Interval: 5 * time.Second, Retries: 3}, config)
+
+	_, err = (&Healthcheck{Test: []string{"curl", "-f", "localhost"}}).healthConfig()
+	assert.EqualError(t, err, `Healthcheck test must start with CMD, CMD-SHELL or NONE, not "curl"`)
+	_, err = (&Healthcheck{Test: []string{"CMD"}}).healthConfig()
+	assert.EqualError(t, err, "Healthcheck test CMD requires a command")
+	_, err = (&Healthcheck{Timeout: time.Microsecond}).healthConfig()
+	assert.EqualError(t, err, "Healthcheck timeout must be 0 or at least 1ms, got 1µs")
+	_, err = (&Healthcheck{Retries: -1}).healthConfig()
+	assert.Error(t, err)
+}
+
+func TestContainerHealth(t *testing.T) {
+	c := &Container{Container: &docker.Container{}}
+	assert.Equal(t, HealthResult{Status: HealthNone}, c.Health())
+
+	c.Container.State.Health = docker.Health{
+		Status:        HealthUnhealthy,
+		FailingStreak: 3,
+		Log:           []docker.HealthCheck{{ExitCode: 1, Output: "connection refused"}},
+	}
+	health := c.Health()
+	assert.Equal(t, HealthUnhealthy, health.Status)
+	assert.Equal(t, []HealthProbe{{ExitCode: 1, Output: "connection refused"}}, health.Probes)
+	assert.Equal(t, ` : "connection refused"`, health.lastOutput())
+}
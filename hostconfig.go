@@ -0,0 +1,232 @@
+package dockerapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Restart policies of containers
+const (
+	RestartNo            = "no"
+	RestartAlways        = "always"
+	RestartUnlessStopped = "unless-stopped"
+	RestartOnFailure     = "on-failure"
+)
+
+// RestartPolicy defines when the engine restarts a container
+type RestartPolicy struct {
+	Name              string // Restart policy (ex : RestartAlways). RestartNo if empty
+	MaximumRetryCount int    // Maximum number of restarts, only for RestartOnFailure. Unlimited if 0
+}
+
+// Ulimit is a resource limit of the processes of a container
+type Ulimit struct {
+	Name string // Name of the limit (ex : nofile, nproc)
+	Soft int64  // Soft limit
+	Hard int64  // Hard limit, equal to the soft limit if 0
+}
+
+// LogConfig defines the logging driver of a container
+type LogConfig struct {
+	Type   string            // Logging driver (ex : json-file, syslog)
+	Config map[string]string // Options of the driver (ex : max-size=10m)
+}
+
+// applyHostConfig validates the options of the host config and sets them, with their default values
+func (o ContainerOptions) applyHostConfig(hc *docker.HostConfig) error {
+	policy, err := o.RestartPolicy.restartPolicy()
+	if err != nil {
+		return err
+	}
+	ulimits, err := ulimits(o.Ulimits)
+	if err != nil {
+		return err
+	}
+	devices, err := devices(o.Devices)
+	if err != nil {
+		return err
+	}
+	capAdd, err := capabilities(o.CapAdd)
+	if err != nil {
+		return err
+	}
+	capDrop, err := capabilities(o.CapDrop)
+	if err != nil {
+		return err
+	}
+	if err := validateSecurityOpt(o.SecurityOpt); err != nil {
+		return err
+	}
+	if err := validateTmpfs(o.Tmpfs); err != nil {
+		return err
+	}
+	if err := validateDNS(o.DNS); err != nil {
+		return err
+	}
+	if err := o.Parameters.validate(); err != nil {
+		return err
+	}
+	if o.LogConfig.Type == "" && len(o.LogConfig.Config) > 0 {
+		return errors.New("Log config requires a driver type")
+	}
+
+	hc.RestartPolicy = policy
+	hc.CapAdd = capAdd
+	hc.CapDrop = capDrop
+	hc.Privileged = o.Privileged
+	hc.ReadonlyRootfs = o.ReadonlyRootfs
+	hc.SecurityOpt = o.SecurityOpt
+	hc.Ulimits = ulimits
+	hc.Tmpfs = o.Tmpfs
+	hc.Devices = devices
+	hc.DNS = o.DNS
+	hc.DNSSearch = o.DNSSearch
+	hc.LogConfig = docker.LogConfig{Type: o.LogConfig.Type, Config: o.LogConfig.Config}
+	hc.CPUQuota = o.Parameters.CPUQuota
+	hc.CPUPeriod = o.Parameters.CPUPeriod
+	hc.NanoCPUs = o.Parameters.NanoCPUs
+	hc.ShmSize = o.Parameters.ShmSize
+	if o.Parameters.PidsLimit != 0 {
+		pidsLimit := o.Parameters.PidsLimit
+		hc.PidsLimit = &pidsLimit
+	}
+	if o.Parameters.OomKillDisable {
+		oomKillDisable := true
+		hc.OOMKillDisable = &oomKillDisable
+	}
+	return nil
+}
+
+func (p RestartPolicy) restartPolicy() (docker.RestartPolicy, error) {
+	switch p.Name {
+	case "", RestartNo, RestartAlways, RestartUnlessStopped:
+		if p.MaximumRetryCount != 0 {
+			return docker.RestartPolicy{}, fmt.Errorf("Maximum retry count is only allowed with restart policy %v", RestartOnFailure)
+		}
+	case RestartOnFailure:
+		if p.MaximumRetryCount < 0 {
+			return docker.RestartPolicy{}, fmt.Errorf("Maximum retry count can't be negative, got %v", p.MaximumRetryCount)
+		}
+	default:
+		return docker.RestartPolicy{}, fmt.Errorf("Unknown restart policy %q", p.Name)
+	}
+	if p.Name == "" {
+		p.Name = RestartNo
+	}
+	return docker.RestartPolicy{Name: p.Name, MaximumRetryCount: p.MaximumRetryCount}, nil
+}
+
+func ulimits(limits []Ulimit) ([]docker.ULimit, error) {
+	res := []docker.ULimit{}
+	for _, l := range limits {
+		if l.Name == "" {
+			return nil, errors.New("Ulimit name is required")
+		}
+		if l.Hard == 0 {
+			l.Hard = l.Soft
+		}
+		if l.Soft > l.Hard {
+			return nil, fmt.Errorf("Ulimit %v : soft limit %v is greater than hard limit %v", l.Name, l.Soft, l.Hard)
+		}
+		res = append(res, docker.ULimit{Name: l.Name, Soft: l.Soft, Hard: l.Hard})
+	}
+	return res, nil
+}
+
+// devices parses the devices. Format : hostpath[:containerpath[:permissions]]
+func devices(devices []string) ([]docker.Device, error) {
+	res := []docker.Device{}
+	for _, device := range devices {
+		parts := strings.Split(device, ":")
+		if len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid device %q. Format : hostpath[:containerpath[:permissions]]", device)
+		}
+		d := docker.Device{PathOnHost: parts[0], PathInContainer: parts[0], CgroupPermissions: "rwm"}
+		if len(parts) > 1 && parts[1] != "" {
+			d.PathInContainer = parts[1]
+		}
+		if len(parts) > 2 {
+			d.CgroupPermissions = parts[2]
+			if d.CgroupPermissions == "" || strings.Trim(d.CgroupPermissions, "rwm") != "" {
+				return nil, fmt.Errorf("Invalid permissions %q of device %v. Format : any of r, w and m", d.CgroupPermissions, parts[0])
+			}
+		}
+		if !strings.HasPrefix(d.PathOnHost, "/") || !strings.HasPrefix(d.PathInContainer, "/") {
+			return nil, fmt.Errorf("Invalid device %q. Paths must be absolute", device)
+		}
+		res = append(res, d)
+	}
+	return res, nil
+}
+
+// capabilities normalizes the capabilities : upper case, without the CAP_ prefix
+func capabilities(caps []string) ([]string, error) {
+	res := []string{}
+	for _, c := range caps {
+		c = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(c)), "CAP_")
+		if c == "" {
+			return nil, errors.New("Capability can't be empty")
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func validateSecurityOpt(opts []string) error {
+	for _, opt := range opts {
+		if opt == "no-new-privileges" {
+			continue
+		}
+		key, value, ok := strings.Cut(opt, "=")
+		if !ok {
+			// Legacy format : key:value
+			key, value, ok = strings.Cut(opt, ":")
+		}
+		if !ok || key == "" || value == "" {
+			return fmt.Errorf("Invalid security option %q. Format : key=value", opt)
+		}
+	}
+	return nil
+}
+
+func validateTmpfs(tmpfs map[string]string) error {
+	for path := range tmpfs {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("Invalid tmpfs path %q. Path must be absolute", path)
+		}
+	}
+	return nil
+}
+
+func validateDNS(servers []string) error {
+	for _, server := range servers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("Invalid DNS server %q. Format : IP address", server)
+		}
+	}
+	return nil
+}
+
+// validate checks the CPU and process limits
+func (p Parameters) validate() error {
+	if p.NanoCPUs < 0 || p.CPUQuota < 0 || p.CPUPeriod < 0 || p.ShmSize < 0 {
+		return errors.New("CPU limits and shm size can't be negative")
+	}
+	if p.NanoCPUs > 0 && (p.CPUQuota > 0 || p.CPUPeriod > 0) {
+		return errors.New("NanoCPUs can't be used with CPUQuota or CPUPeriod")
+	}
+	if p.CPUQuota > 0 && p.CPUQuota < 1000 {
+		return fmt.Errorf("CPUQuota must be at least 1000 microseconds, got %v", p.CPUQuota)
+	}
+	if p.CPUPeriod > 0 && (p.CPUPeriod < 1000 || p.CPUPeriod > 1000000) {
+		return fmt.Errorf("CPUPeriod must be between 1000 and 1000000 microseconds, got %v", p.CPUPeriod)
+	}
+	if p.PidsLimit < -1 {
+		return fmt.Errorf("PidsLimit must be -1 (unlimited) or positive, got %v", p.PidsLimit)
+	}
+	return nil
+}
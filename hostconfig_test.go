@@ -0,0 +1,55 @@
+package dockerapi
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyHostConfig(t *testing.T) {
+	o := ContainerOptions{
+		RestartPolicy: RestartPolicy{Name: RestartOnFailure, MaximumRetryCount: 3},
+		CapAdd:        []string{"cap_net_admin", "SYS_TIME"},
+		SecurityOpt:   []string{"no-new-privileges", "seccomp=unconfined"},
+		Ulimits:       []Ulimit{{Name: "nofile", Soft: 1024}},
+		Devices:       []string{"/dev/fuse", "/dev/sda:/dev/xvda:r"},
+		DNS:           []string{"8.8.8.8"},
+		LogConfig:     LogConfig{Type: "json-file", Config: map[string]string{"max-size": "10m"}},
+		Parameters:    Parameters{NanoCPUs: 1500000000, PidsLimit: 100},
+	}
+	hc := &docker.HostConfig{}
+	assert.NoError(t, o.applyHostConfig(hc))
+	assert.Equal(t, docker.RestartPolicy{Name: "on-failure", MaximumRetryCount: 3}, hc.RestartPolicy)
+	assert.Equal(t, []string{"NET_ADMIN", "SYS_TIME"}, hc.CapAdd)
+	assert.Equal(t, []docker.ULimit{{Name: "nofile", Soft: 1024, Hard: 1024}}, hc.Ulimits)
+	assert.Equal(t, []docker.Device{
+		{PathOnHost: "/dev/fuse", PathInContainer: "/dev/fuse", CgroupPermissions: "rwm"},
+		{PathOnHost: "/dev/sda", PathInContainer: "/dev/xvda", CgroupPermissions: "r"},
+	}, hc.Devices)
+	assert.Equal(t, int64(100), *hc.PidsLimit)
+	assert.Nil(t, hc.OOMKillDisable)
+
+	hc = &docker.HostConfig{}
+	assert.NoError(t, ContainerOptions{}.applyHostConfig(hc))
+	assert.Equal(t, docker.RestartPolicy{Name: "no"}, hc.RestartPolicy)
+	assert.Nil(t, hc.PidsLimit)
+}
+
+func TestApplyHostConfigErrors(t *testing.T) {
+	tests := map[string]ContainerOptions{
+		`Unknown restart policy "sometimes"`:                                      {RestartPolicy: RestartPolicy{Name: "sometimes"}},
+		"Maximum retry count is only allowed with restart policy on-failure":      {RestartPolicy: RestartPolicy{Name: RestartAlways, MaximumRetryCount: 2}},
+		"Ulimit nofile : soft limit 2048 is greater than hard limit 1024":         {Ulimits: []Ulimit{{Name: "nofile", Soft: 2048, Hard: 1024}}},
+		`Invalid permissions "rx" of device /dev/sda. Format : any of r, w and m`: {Devices: []string{"/dev/sda:/dev/sda:rx"}},
+		`Invalid security option "seccomp". Format : key=value`:                   {SecurityOpt: []string{"seccomp"}},
+		`Invalid tmpfs path "run". Path must be absolute`:                         {Tmpfs: map[string]string{"run": ""}},
+		`Invalid DNS server "dns.local". Format : IP address`:                     {DNS: []string{"dns.local"}},
+		"NanoCPUs can't be used with CPUQuota or CPUPeriod":                       {Parameters: Parameters{NanoCPUs: 1, CPUQuota: 5000}},
+		"CPUPeriod must be between 1000 and 1000000 microseconds, got 10":         {Parameters: Parameters{CPUPeriod: 10}},
+		"Log config requires a driver type":                                       {LogConfig: LogConfig{Config: map[string]string{"max-size": "10m"}}},
+	}
+	for expected, o := range tests {
+		assert.EqualError(t, o.applyHostConfig(&docker.HostConfig{}), expected)
+	}
+}
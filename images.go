@@ -1,6 +1,8 @@
 package dockerapi
 
 import (
+	"context"
+	"fmt"
 	"io"
 
 	"github.com/fsouza/go-dockerclient"
@@ -8,22 +10,76 @@ import (
 
 // PullImage pulls an Docker image
 func (c *Client) PullImage(image string) error {
-	return c.PullImageAsync(image, nil)
+	return c.PullImageWithContext(context.Background(), image)
+}
+
+// PullImageWithContext pulls an Docker image. The pull is aborted when the context is done
+func (c *Client) PullImageWithContext(ctx context.Context, image string) error {
+	return c.PullImageAsyncWithContext(ctx, image, nil)
 }
 
 // PullImageAsync pull the given image and progress can be followed asynchronously, by providing a writer
 func (c *Client) PullImageAsync(image string, progressDetail io.Writer) error {
+	return c.PullImageAsyncWithContext(context.Background(), image, progressDetail)
+}
+
+// PullImageAsyncWithContext pull the given image and progress can be followed asynchronously, by providing a writer
+// Credentials of the image registry are taken from the client
+// The pull is aborted when the context is done
+func (c *Client) PullImageAsyncWithContext(ctx context.Context, image string, progressDetail io.Writer) error {
+	return c.pullImage(ctx, image, progressDetail, false)
+}
+
+// PullImageWithProgress pulls the given image, sending typed progress events to the progress function if not nil
+// Errors reported by the engine in the progress stream are returned
+// The pull is aborted when the context is done
+func (c *Client) PullImageWithProgress(ctx context.Context, image string, progress func(PullProgress)) error {
+	r, w := io.Pipe()
+	decoded := make(chan error, 1)
+	go func() {
+		err := decodePullProgress(r, progress)
+		// Unblocks the engine stream if decoding stopped before its end
+		r.CloseWithError(err)
+		decoded <- err
+	}()
+
+	err := c.pullImage(ctx, image, w, true)
+	w.CloseWithError(err)
+	streamErr := <-decoded
+	// The error of the pull comes first : the stream is only interrupted because of it
+	if err != nil {
+		return fmt.Errorf("Can't pull image %v : %w", image, err)
+	}
+	if streamErr != nil {
+		return fmt.Errorf("Can't pull image %v : %w", image, streamErr)
+	}
+	return nil
+}
+
+// pullImage pulls the image with the credentials of its registry, writing the engine stream to output
+// If rawJSON is true, the JSON messages of the engine are written as is
+func (c *Client) pullImage(ctx context.Context, image string, output io.Writer, rawJSON bool) error {
 	options := docker.PullImageOptions{
-		Repository:   image,
-		OutputStream: progressDetail,
+		Repository:    image,
+		OutputStream:  output,
+		RawJSONStream: rawJSON,
+		Context:       ctx,
+	}
+	auth, err := c.registryAuth(ctx, image)
+	if err != nil {
+		return err
 	}
-	auth := docker.AuthConfiguration{}
 	return c.Docker.PullImage(options, auth)
 }
 
 // RemoveImage safely removes the image
 func (c *Client) RemoveImage(image string) error {
-	return c.Docker.RemoveImage(image)
+	return c.RemoveImageWithContext(context.Background(), image)
+}
+
+// RemoveImageWithContext safely removes the image. The removal is aborted when the context is done
+func (c *Client) RemoveImageWithContext(ctx context.Context, image string) error {
+	return c.Docker.RemoveImageExtended(image, docker.RemoveImageOptions{Context: ctx})
 }
 
 // ImageExists checks if an image exists on the server
@@ -31,3 +87,19 @@ func (c *Client) ImageExists(image string) bool {
 	_, err := c.Docker.InspectImage(image)
 	return err == nil
 }
+
+// ImageExistsWithContext checks if an image exists on the server
+// Returns false as soon as the context is done
+func (c *Client) ImageExistsWithContext(ctx context.Context, image string) bool {
+	// The engine client can't cancel an image inspection, so the check is raced against the context
+	exists := make(chan bool, 1)
+	go func() {
+		exists <- c.ImageExists(image)
+	}()
+	select {
+	case res := <-exists:
+		return res
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,69 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingCredentials struct{ err error }
+
+func (f failingCredentials) Credentials(ctx context.Context, registry string) (docker.AuthConfiguration, error) {
+	return docker.AuthConfiguration{}, f.err
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	client, err := docker.NewClient(server.URL)
+	assert.NoError(t, err)
+	return &Client{Docker: client}
+}
+
+func TestPullImageWithProgressErrors(t *testing.T) {
+	exploded := errors.New("helper exploded")
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request %v", r.URL)
+	})
+	client.Credentials = failingCredentials{exploded}
+	err := client.PullImageWithProgress(context.Background(), "redis", nil)
+	assert.EqualError(t, err, "Can't pull image redis : helper exploded")
+	assert.True(t, errors.Is(err, exploded))
+
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "manifest unknown", http.StatusNotFound)
+	})
+	err = client.PullImageWithProgress(context.Background(), "redis:nope", nil)
+	var engineErr *docker.Error
+	assert.True(t, errors.As(err, &engineErr))
+	assert.Equal(t, http.StatusNotFound, engineErr.Status)
+
+	client = newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`))
+	})
+	err = client.PullImageWithProgress(context.Background(), "redis:nope", nil)
+	assert.Error(t, err)
+	assert.EqualError(t, err, "Can't pull image redis:nope : manifest unknown")
+}
+
+func TestRunReturnsPullError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/images/create" {
+			w.Write([]byte(`{"errorDetail":{"message":"manifest unknown"},"error":"manifest unknown"}`))
+			return
+		}
+		http.NotFound(w, r)
+	})
+	c, err := client.NewContainer(ContainerOptions{Image: "redis:nope", Name: "cache"})
+	assert.NoError(t, err)
+	err = c.RunWithContext(context.Background(), false)
+	assert.EqualError(t, err, "Can't pull image redis:nope : manifest unknown")
+
+	err = PoolContainer{c}.RunAllWithOptions(context.Background(), false, PoolOptions{})
+	assert.EqualError(t, err, "1 of 1 containers failed : cache: Can't pull image redis:nope : manifest unknown")
+}
@@ -0,0 +1,434 @@
+package dockerapi
+
+import (
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// LogsOptions is used to get logs from container
+type LogsOptions struct {
+	OutputStream io.Writer
+	ErrorStream  io.Writer
+	Stdout       bool
+	Stderr       bool
+	Tail         string    // Number of lines to get from the end of the logs, "all" if empty
+	Follow       bool      // Keeps streaming new logs until the context is done
+	Timestamps   bool      // Prefixes each line with its RFC3339Nano timestamp
+	Since        time.Time // Only logs written after this time. All logs if zero
+	Until        time.Time // Only logs written before this time. All logs if zero
+	Details      bool      // Prefixes each line with the extra attributes given to the log driver
+	ParseJSON    bool      // Parses JSON formatted lines into LogEntry.Fields. Only used by LogEntries and StreamLogs
+}
+
+// logPartialSize is the size of the parts of long lines, split by the log copier of the engine
+// Each part is sent as a log message with its own timestamp
+const logPartialSize = 16 * 1024
+
+// LogStream identifies the output of a container a log line comes from
+type LogStream string
+
+// Outputs of a container
+const (
+	LogStdout LogStream = "stdout"
+	LogStderr LogStream = "stderr"
+)
+
+// LogEntry is a log line of a container
+type LogEntry struct {
+	Time   time.Time              // Time the line was written
+	Stream LogStream              // Output of the container
+	Line   string                 // Line without timestamp, details nor line ending
+	Attrs  map[string]string      // Extra attributes of the log driver, when requested with LogsOptions.Details
+	Fields map[string]interface{} // Fields of a JSON formatted line, when requested with LogsOptions.ParseJSON
+}
+
+// Logs get the logs from the container
+// Logs are only followed and timestamped when requested with LogsOptions.Follow and LogsOptions.Timestamps
+func (c *Container) Logs(opts LogsOptions) error {
+	return c.LogsWithContext(context.Background(), opts)
+}
+
+// LogsWithContext get the logs from the container
+// When following logs, they are streamed until the context is done
+func (c *Container) LogsWithContext(ctx context.Context, opts LogsOptions) error {
+	query := url.Values{}
+	query.Set("stdout", boolParam(opts.Stdout))
+	query.Set("stderr", boolParam(opts.Stderr))
+	query.Set("follow", boolParam(opts.Follow))
+	query.Set("timestamps", boolParam(opts.Timestamps))
+	query.Set("details", boolParam(opts.Details))
+	tail := opts.Tail
+	if tail == "" {
+		tail = "all"
+	}
+	query.Set("tail", tail)
+	if !opts.Since.IsZero() {
+		query.Set("since", timeParam(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", timeParam(opts.Until))
+	}
+
+	resp, err := c.Client.request(ctx, http.MethodGet, "/containers/"+c.ID()+"/logs", query, nil)
+	if err != nil {
+		return fmt.Errorf("Can't get logs from container %v because : %w", c.ShortID(), err)
+	}
+	defer resp.Body.Close()
+
+	stdout, stderr := opts.OutputStream, opts.ErrorStream
+	if stdout == nil {
+		stdout = io.Discard
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	if c.hasTty() {
+		// Outputs of a TTY container are merged and not multiplexed
+		_, err = io.Copy(stdout, resp.Body)
+	} else {
+		err = demuxStream(resp.Body, stdout, stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("Can't get logs from container %v because : %w", c.ShortID(), err)
+	}
+	return nil
+}
+
+// LogEntries returns the logs from the container, with parsed timestamps
+// Both outputs are returned if neither opts.Stdout nor opts.Stderr is set. Writers of opts are ignored
+// When following logs, entries are only returned once the context is done
+func (c *Container) LogEntries(ctx context.Context, opts LogsOptions) ([]LogEntry, error) {
+	entries := []LogEntry{}
+	err := c.logEntries(ctx, opts, func(entry LogEntry) {
+		entries = append(entries, entry)
+	})
+	return entries, err
+}
+
+// StreamLogs sends the logs from the container as entries with parsed timestamps, as soon as they are read
+// Both outputs are sent if neither opts.Stdout nor opts.Stderr is set. Writers of opts are ignored
+// The entries channel is closed at the end of the logs, or when the context is done if logs are followed
+// The error channel then receives the error that stopped the logs, if any, and is closed
+func (c *Container) StreamLogs(ctx context.Context, opts LogsOptions) (<-chan LogEntry, <-chan error) {
+	entries := make(chan LogEntry)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		err := c.logEntries(ctx, opts, func(entry LogEntry) {
+			select {
+			case entries <- entry:
+			case <-ctx.Done():
+			}
+		})
+		close(entries)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return entries, errs
+}
+
+// logEntries gets the logs from the container with timestamps, sending each entry to onEntry
+// The end of followed logs because the context is done is not an error
+func (c *Container) logEntries(ctx context.Context, opts LogsOptions, onEntry func(LogEntry)) error {
+	stdout := newLogEntryWriter(LogStdout, opts, onEntry)
+	stderr := newLogEntryWriter(LogStderr, opts, onEntry)
+
+	opts.Timestamps = true
+	opts.OutputStream = stdout
+	opts.ErrorStream = stderr
+	if !opts.Stdout && !opts.Stderr {
+		opts.Stdout, opts.Stderr = true, true
+	}
+	err := c.LogsWithContext(ctx, opts)
+	stdout.Flush()
+	stderr.Flush()
+	if err != nil && !(opts.Follow && ctx.Err() != nil) {
+		return err
+	}
+	return nil
+}
+
+// hasTty checks whether the container allocates a TTY
+func (c *Container) hasTty() bool {
+	return c.Container != nil && c.Container.Config != nil && c.Container.Config.Tty
+}
+
+// newLogEntryWriter creates a writer parsing each line of an output written with timestamps into a log entry
+// Outputs of TTY containers are all written to stdout, with \r\n line endings handled by the line writer
+func newLogEntryWriter(stream LogStream, opts LogsOptions, onEntry func(LogEntry)) *lineWriter {
+	return newLineWriter(func(line string) {
+		onEntry(parseLogLine(stream, line, opts.Details, opts.ParseJSON))
+	})
+}
+
+// parseLogLine parses a log line prefixed with its timestamp, and its details if requested
+// Parts of a long line split by the engine are joined back
+// The whole line is kept if it has no valid timestamp
+func parseLogLine(stream LogStream, line string, details, parseJSON bool) LogEntry {
+	entry := LogEntry{Stream: stream, Line: line}
+	if t, rest, ok := cutTimestamp(line); ok {
+		entry.Time = t
+		if details {
+			entry.Attrs, rest = cutDetails(rest)
+		}
+		entry.Line = joinPartialLines(rest, details)
+	}
+	if parseJSON && strings.HasPrefix(strings.TrimSpace(entry.Line), "{") {
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(entry.Line), &fields) == nil {
+			entry.Fields = fields
+		}
+	}
+	return entry
+}
+
+// cutTimestamp parses the RFC3339Nano timestamp prefixing a log line and returns the rest of the line
+func cutTimestamp(line string) (time.Time, string, bool) {
+	prefix, rest, _ := strings.Cut(line, " ")
+	t, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, line, false
+	}
+	return t, rest, true
+}
+
+// cutDetails parses the details prefixing a log line and returns the rest of the line
+// Details are comma separated key=value pairs, empty if the log driver has no extra attributes
+func cutDetails(line string) (map[string]string, string) {
+	details, rest, _ := strings.Cut(line, " ")
+	attrs := map[string]string{}
+	for _, attr := range strings.Split(details, ",") {
+		if key, value, ok := strings.Cut(attr, "="); ok {
+			key, _ = url.QueryUnescape(key)
+			value, _ = url.QueryUnescape(value)
+			attrs[key] = value
+		}
+	}
+	return attrs, rest
+}
+
+// joinPartialLines joins the parts of a line split by the engine
+// A part has no line ending, so the timestamp (and details) of the next part directly follows it
+func joinPartialLines(line string, details bool) string {
+	var joined strings.Builder
+	for len(line) > logPartialSize {
+		_, rest, ok := cutTimestamp(line[logPartialSize:])
+		if !ok {
+			break
+		}
+		if details {
+			_, rest = cutDetails(rest)
+		}
+		joined.WriteString(line[:logPartialSize])
+		line = rest
+	}
+	joined.WriteString(line)
+	return joined.String()
+}
+
+// demuxStream copies a stream multiplexed by the engine to the writers of each output
+// Each frame has a header of 8 bytes : the output (0: stdin, 1: stdout, 2: stderr, 3: error), 3 empty bytes, then the size of the payload
+func demuxStream(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		switch header[0] {
+		case 0, 1:
+			if _, err := io.CopyN(stdout, r, size); err != nil {
+				return err
+			}
+		case 2:
+			if _, err := io.CopyN(stderr, r, size); err != nil {
+				return err
+			}
+		case 3:
+			message := make([]byte, size)
+			if _, err := io.ReadFull(r, message); err != nil {
+				return err
+			}
+			return errors.New(string(message))
+		default:
+			return fmt.Errorf("Unknown output %v in multiplexed stream", header[0])
+		}
+	}
+}
+
+// boolParam formats a boolean query parameter of the engine API
+func boolParam(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// timeParam formats a time query parameter of the engine API, as seconds and nanoseconds since epoch
+func timeParam(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// defaultLogReorderWindow is the default time logs of a pool are held to be sorted by timestamp
+const defaultLogReorderWindow = 500 * time.Millisecond
+
+// defaultLogReconnectDelay is the default delay before following again the logs of a stopped container of a pool
+const defaultLogReconnectDelay = time.Second
+
+// PoolLogsOptions is used to follow logs from all containers of a pool
+type PoolLogsOptions struct {
+	LogsOptions                  // Options used for each container. Logs are always followed
+	ReorderWindow  time.Duration // Time entries are held to be sorted by timestamp. 500ms if 0
+	ReconnectDelay time.Duration // Delay before following again the logs of a stopped container. 1s if 0
+}
+
+// PoolLogEntry is a log line of a container of a pool
+type PoolLogEntry struct {
+	LogEntry
+	Container string // Name of the container
+}
+
+// String formats the entry prefixed by its container name, like docker-compose
+func (e PoolLogEntry) String() string {
+	return e.Container + " | " + e.Line
+}
+
+// FollowLogs follows the logs of all containers of the pool, merged in a single stream ordered by timestamp
+// Logs of a container are followed again when it restarts, until it is removed
+// The channel is closed when the context is done
+func (pool PoolContainer) FollowLogs(ctx context.Context, opts PoolLogsOptions) <-chan PoolLogEntry {
+	if opts.ReorderWindow <= 0 {
+		opts.ReorderWindow = defaultLogReorderWindow
+	}
+	if opts.ReconnectDelay <= 0 {
+		opts.ReconnectDelay = defaultLogReconnectDelay
+	}
+	opts.Follow = true
+
+	in := make(chan PoolLogEntry)
+	out := make(chan PoolLogEntry)
+	var wg sync.WaitGroup
+	for _, c := range pool {
+		wg.Add(1)
+		go func(c *Container) {
+			defer wg.Done()
+			c.followLogs(ctx, opts, in)
+		}(c)
+	}
+	go func() {
+		wg.Wait()
+		close(in)
+	}()
+	go mergeLogs(ctx, in, out, opts.ReorderWindow)
+	return out
+}
+
+// followLogs sends the logs of the container to entries until the context is done or the container is removed
+// Logs are read again from the last entry when the stream ends, as it does when the container stops
+func (c *Container) followLogs(ctx context.Context, opts PoolLogsOptions, entries chan<- PoolLogEntry) {
+	name := c.Name()
+	logsOpts := opts.LogsOptions
+	for {
+		err := c.logEntries(ctx, logsOpts, func(entry LogEntry) {
+			// Next connection starts right after the last entry
+			logsOpts.Since = entry.Time.Add(time.Nanosecond)
+			logsOpts.Tail = "all"
+			select {
+			case entries <- PoolLogEntry{LogEntry: entry, Container: name}:
+			case <-ctx.Done():
+			}
+		})
+		var apiErr *docker.Error
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+			log.Printf("Container %v is removed, its logs are not followed anymore", name)
+			return
+		}
+		if err != nil {
+			log.Printf("Logs of container %v interrupted : %v", name, err)
+		}
+		select {
+		case <-time.After(opts.ReconnectDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// mergeLogs sends entries to out, ordered by timestamp, and closes out at the end of entries or when the context is done
+// Entries are held until an entry more recent by the reorder window is received, or until no entry is received during the window
+func mergeLogs(ctx context.Context, in <-chan PoolLogEntry, out chan<- PoolLogEntry, window time.Duration) {
+	defer close(out)
+	pending := &logHeap{}
+	var latest time.Time
+	lastReceived := time.Now()
+	ticker := time.NewTicker(window / 2)
+	defer ticker.Stop()
+
+	// flush sends pending entries older than the limit, all of them if the limit is zero
+	flush := func(limit time.Time) bool {
+		for pending.Len() > 0 && (limit.IsZero() || !(*pending)[0].Time.After(limit)) {
+			select {
+			case out <- heap.Pop(pending).(PoolLogEntry):
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		select {
+		case entry, ok := <-in:
+			if !ok {
+				flush(time.Time{})
+				return
+			}
+			heap.Push(pending, entry)
+			lastReceived = time.Now()
+			if entry.Time.After(latest) {
+				latest = entry.Time
+			}
+			if !flush(latest.Add(-window)) {
+				return
+			}
+		case <-ticker.C:
+			if time.Since(lastReceived) >= window && !flush(time.Time{}) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// logHeap is a heap of entries ordered by timestamp
+type logHeap []PoolLogEntry
+
+func (h logHeap) Len() int            { return len(h) }
+func (h logHeap) Less(i, j int) bool  { return h[i].Time.Before(h[j].Time) }
+func (h logHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *logHeap) Push(x interface{}) { *h = append(*h, x.(PoolLogEntry)) }
+func (h *logHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
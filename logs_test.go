@@ -0,0 +1,153 @@
+package dockerapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// frame builds a frame of a stream multiplexed by the engine
+func frame(output byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = output
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestDemuxStream(t *testing.T) {
+	stream := append(frame(1, "out 1\n"), frame(2, "err 1\n")...)
+	stream = append(stream, frame(1, "out 2\n")...)
+	var stdout, stderr bytes.Buffer
+	assert.NoError(t, demuxStream(bytes.NewReader(stream), &stdout, &stderr))
+	assert.Equal(t, "out 1\nout 2\n", stdout.String())
+	assert.Equal(t, "err 1\n", stderr.String())
+
+	stream = append(frame(1, "out\n"), frame(3, "logs unavailable")...)
+	assert.EqualError(t, demuxStream(bytes.NewReader(stream), &stdout, &stderr), "logs unavailable")
+
+	// Truncated payload
+	assert.Error(t, demuxStream(bytes.NewReader(frame(1, "out\n")[:10]), &stdout, &stderr))
+}
+
+func TestParseLogLine(t *testing.T) {
+	entry := parseLogLine(LogStderr, "2017-06-01T10:20:30.123456789Z hello world", false, false)
+	assert.Equal(t, time.Date(2017, 6, 1, 10, 20, 30, 123456789, time.UTC), entry.Time.UTC())
+	assert.Equal(t, LogStderr, entry.Stream)
+	assert.Equal(t, "hello world", entry.Line)
+	assert.Nil(t, entry.Attrs)
+
+	entry = parseLogLine(LogStdout, "2017-06-01T10:20:30Z", false, false)
+	assert.Equal(t, "", entry.Line)
+	assert.False(t, entry.Time.IsZero())
+
+	entry = parseLogLine(LogStdout, "no timestamp here", false, false)
+	assert.True(t, entry.Time.IsZero())
+	assert.Equal(t, "no timestamp here", entry.Line)
+
+	entry = parseLogLine(LogStdout, "2017-06-01T10:20:30Z env=prod,service=a%20b hello", true, false)
+	assert.Equal(t, map[string]string{"env": "prod", "service": "a b"}, entry.Attrs)
+	assert.Equal(t, "hello", entry.Line)
+
+	entry = parseLogLine(LogStdout, "2017-06-01T10:20:30Z  hello", true, false)
+	assert.Equal(t, map[string]string{}, entry.Attrs)
+	assert.Equal(t, "hello", entry.Line)
+}
+
+func TestParseLogLinePartial(t *testing.T) {
+	part1 := strings.Repeat("a", logPartialSize)
+	part2 := strings.Repeat("b", logPartialSize)
+	line := "2017-06-01T10:20:30Z " + part1 + "2017-06-01T10:20:31Z " + part2 + "2017-06-01T10:20:32Z end"
+	entry := parseLogLine(LogStdout, line, false, false)
+	assert.Equal(t, part1+part2+"end", entry.Line)
+	assert.Equal(t, 30, entry.Time.Second())
+
+	line = "2017-06-01T10:20:30Z k=v " + part1 + "2017-06-01T10:20:31Z k=v end"
+	entry = parseLogLine(LogStdout, line, true, false)
+	assert.Equal(t, part1+"end", entry.Line)
+	assert.Equal(t, map[string]string{"k": "v"}, entry.Attrs)
+
+	// Long line without part
+	entry = parseLogLine(LogStdout, "2017-06-01T10:20:30Z "+part1+" not a timestamp", false, false)
+	assert.Equal(t, part1+" not a timestamp", entry.Line)
+}
+
+func TestParseLogLineJSON(t *testing.T) {
+	entry := parseLogLine(LogStdout, `2017-06-01T10:20:30Z {"level":"error","msg":"boom","code":42}`, false, true)
+	assert.Equal(t, map[string]interface{}{"level": "error", "msg": "boom", "code": float64(42)}, entry.Fields)
+	assert.Equal(t, `{"level":"error","msg":"boom","code":42}`, entry.Line)
+
+	entry = parseLogLine(LogStdout, `2017-06-01T10:20:30Z {"level":`, false, true)
+	assert.Nil(t, entry.Fields)
+	entry = parseLogLine(LogStdout, `2017-06-01T10:20:30Z {"level":"info"}`, false, false)
+	assert.Nil(t, entry.Fields)
+}
+
+func TestLogEntryWriter(t *testing.T) {
+	entries := []LogEntry{}
+	w := newLogEntryWriter(LogStdout, LogsOptions{}, func(e LogEntry) {
+		entries = append(entries, e)
+	})
+	// TTY containers write \r\n line endings, possibly across several writes
+	w.Write([]byte("2017-06-01T10:20:30Z first\r\n2017-06-01T10:20:31Z sec"))
+	w.Write([]byte("ond\r\n2017-06-01T10:20:32Z last"))
+	w.Flush()
+	lines := []string{}
+	for _, e := range entries {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []string{"first", "second", "last"}, lines)
+}
+
+func TestTimeParam(t *testing.T) {
+	assert.Equal(t, "1496312430.000000042", timeParam(time.Unix(1496312430, 42)))
+}
+
+func TestMergeLogs(t *testing.T) {
+	base := time.Date(2017, 6, 1, 10, 20, 30, 0, time.UTC)
+	entry := func(container string, second int) PoolLogEntry {
+		return PoolLogEntry{LogEntry: LogEntry{Time: base.Add(time.Duration(second) * time.Second)}, Container: container}
+	}
+	in := make(chan PoolLogEntry)
+	out := make(chan PoolLogEntry)
+	go mergeLogs(context.Background(), in, out, time.Minute)
+	go func() {
+		in <- entry("b", 3)
+		in <- entry("a", 1)
+		in <- entry("a", 4)
+		in <- entry("b", 2)
+		close(in)
+	}()
+	merged := []string{}
+	for e := range out {
+		merged = append(merged, fmt.Sprintf("%v%v", e.Container, e.Time.Second()-30))
+	}
+	assert.Equal(t, []string{"a1", "b2", "b3", "a4"}, merged)
+}
+
+func TestMergeLogsWindow(t *testing.T) {
+	base := time.Date(2017, 6, 1, 10, 20, 30, 0, time.UTC)
+	in := make(chan PoolLogEntry)
+	out := make(chan PoolLogEntry)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mergeLogs(ctx, in, out, 50*time.Millisecond)
+
+	// An entry is sent once the window elapsed without other entries
+	in <- PoolLogEntry{LogEntry: LogEntry{Time: base, Line: "first"}, Container: "a"}
+	select {
+	case e := <-out:
+		assert.Equal(t, "a | first", e.String())
+	case <-time.After(time.Second):
+		t.Fatal("entry not sent after the reorder window")
+	}
+
+	cancel()
+	_, ok := <-out
+	assert.False(t, ok)
+}
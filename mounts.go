@@ -0,0 +1,155 @@
+package dockerapi
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/soprasteria/dockerapi/utils"
+)
+
+// MountType is the type of a mount
+type MountType string
+
+// Types of mounts
+const (
+	MountBind   MountType = "bind"   // Path of the host
+	MountVolume MountType = "volume" // Named or anonymous volume
+	MountTmpfs  MountType = "tmpfs"  // Temporary filesystem in memory
+)
+
+// Bind propagations
+var propagations = []string{"private", "rprivate", "shared", "rshared", "slave", "rslave"}
+
+// windowsPath matches absolute Windows paths (ex : C:\data, c:/data)
+var windowsPath = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// Mount is a filesystem mounted inside a container
+// The engine can't relabel mounts for SELinux : binds needing the z or Z option must keep using ContainerOptions.Binds (ex : /data:/data:rw,z)
+type Mount struct {
+	Type     MountType // Type of the mount
+	Source   string    // Path on the host for binds, name of the volume for volumes (anonymous volume if empty). None for tmpfs
+	Target   string    // Path inside the container
+	ReadOnly bool      // Mounts the filesystem as read only
+
+	Propagation string // Propagation of the mounts inside a bind (ex : rshared). rprivate if empty
+
+	NoCopy        bool              // Does not copy the content of the image at the target into a new volume
+	VolumeDriver  string            // Driver creating the volume if it does not exist. local if empty
+	VolumeOptions map[string]string // Options of the volume driver
+	VolumeLabels  map[string]string // Labels of the volume if it is created
+
+	TmpfsSize int64       // Size of the tmpfs in bytes. Unlimited if 0
+	TmpfsMode os.FileMode // Permissions of the tmpfs (ex : 0700). 1777 if 0
+}
+
+// hostMount validates the mount and converts it to the engine format
+func (m Mount) hostMount() (docker.HostMount, error) {
+	if !isAbsolutePath(m.Target) {
+		return docker.HostMount{}, fmt.Errorf("Target %q must be an absolute path", m.Target)
+	}
+	hm := docker.HostMount{Type: string(m.Type), Source: m.Source, Target: m.Target, ReadOnly: m.ReadOnly}
+
+	if m.Type != MountBind && m.Propagation != "" {
+		return hm, fmt.Errorf("Propagation is only allowed for bind mounts, not %v", m.Type)
+	}
+	if m.Type != MountVolume && (m.NoCopy || m.VolumeDriver != "" || len(m.VolumeOptions) > 0 || len(m.VolumeLabels) > 0) {
+		return hm, fmt.Errorf("Volume options are only allowed for volume mounts, not %v", m.Type)
+	}
+	if m.Type != MountTmpfs && (m.TmpfsSize != 0 || m.TmpfsMode != 0) {
+		return hm, fmt.Errorf("Tmpfs size and mode are only allowed for tmpfs mounts, not %v", m.Type)
+	}
+
+	switch m.Type {
+	case MountBind:
+		if !isAbsolutePath(m.Source) {
+			return hm, fmt.Errorf("Source %q of a bind mount must be an absolute path of the host", m.Source)
+		}
+		if m.Propagation == "z" || m.Propagation == "Z" {
+			return hm, fmt.Errorf("SELinux label %q can't be set on a mount, use Binds instead (ex : %v:%v:rw,%v)", m.Propagation, m.Source, m.Target, m.Propagation)
+		}
+		if m.Propagation != "" {
+			if !utils.ContainsString(propagations, m.Propagation) {
+				return hm, fmt.Errorf("Unknown propagation %q. Format : one of %v", m.Propagation, propagations)
+			}
+			hm.BindOptions = &docker.BindOptions{Propagation: m.Propagation}
+		}
+	case MountVolume:
+		if m.VolumeDriver == "" && len(m.VolumeOptions) > 0 {
+			return hm, fmt.Errorf("Volume options of %v require a volume driver", m.Target)
+		}
+		if m.NoCopy || m.VolumeDriver != "" || len(m.VolumeLabels) > 0 {
+			hm.VolumeOptions = &docker.VolumeOptions{
+				NoCopy:       m.NoCopy,
+				Labels:       m.VolumeLabels,
+				DriverConfig: docker.VolumeDriverConfig{Name: m.VolumeDriver, Options: m.VolumeOptions},
+			}
+		}
+	case MountTmpfs:
+		if m.Source != "" {
+			return hm, fmt.Errorf("Tmpfs mounts have no source, got %q", m.Source)
+		}
+		if m.TmpfsSize < 0 {
+			return hm, fmt.Errorf("Tmpfs size can't be negative, got %v", m.TmpfsSize)
+		}
+		if m.TmpfsMode&^os.ModePerm != 0 {
+			return hm, fmt.Errorf("Tmpfs mode %o is not a permission", m.TmpfsMode)
+		}
+		if m.TmpfsSize != 0 || m.TmpfsMode != 0 {
+			hm.TempfsOptions = &docker.TempfsOptions{SizeBytes: m.TmpfsSize, Mode: int(m.TmpfsMode)}
+		}
+	default:
+		return hm, fmt.Errorf("Unknown mount type %q. Format : bind, volume or tmpfs", m.Type)
+	}
+	return hm, nil
+}
+
+// hostMounts validates the mounts and converts them to the engine format
+// A target can only be used once, including by binds and tmpfs of the options
+func (o ContainerOptions) hostMounts() ([]docker.HostMount, error) {
+	targets := map[string]bool{}
+	for _, bind := range o.Binds {
+		if parts := splitBind(bind); len(parts) > 1 {
+			targets[parts[1]] = true
+		}
+	}
+	for target := range o.Tmpfs {
+		targets[target] = true
+	}
+
+	mounts := []docker.HostMount{}
+	for _, m := range o.Mounts {
+		hm, err := m.hostMount()
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %v mount %v : %v", m.Type, m.Target, err)
+		}
+		if targets[m.Target] {
+			return nil, fmt.Errorf("Invalid %v mount %v : target already mounted", m.Type, m.Target)
+		}
+		targets[m.Target] = true
+		mounts = append(mounts, hm)
+	}
+	return mounts, nil
+}
+
+// splitBind splits a volume binding into its parts, keeping the drive letters of Windows paths
+// Format : externalpath:internalpath[:options]
+func splitBind(bind string) []string {
+	parts := []string{}
+	for _, part := range strings.Split(bind, ":") {
+		last := len(parts) - 1
+		if last >= 0 && len(parts[last]) == 1 && windowsPath.MatchString(parts[last]+":"+part) {
+			parts[last] += ":" + part
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// isAbsolutePath checks whether the path is an absolute Unix or Windows path
+func isAbsolutePath(path string) bool {
+	return len(path) > 0 && (path[0] == '/' || windowsPath.MatchString(path))
+}
@@ -0,0 +1,55 @@
+package dockerapi
+
+import (
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostMounts(t *testing.T) {
+	o := ContainerOptions{Mounts: []Mount{
+		{Type: MountBind, Source: `C:\data`, Target: "/data", ReadOnly: true, Propagation: "rshared"},
+		{Type: MountVolume, Source: "db", Target: "/var/lib/postgresql/data", NoCopy: true, VolumeDriver: "local", VolumeOptions: map[string]string{"type": "nfs"}},
+		{Type: MountVolume, Target: "/cache"},
+		{Type: MountTmpfs, Target: "/run", TmpfsSize: 64 << 20, TmpfsMode: 0700},
+	}}
+	mounts, err := o.hostMounts()
+	assert.NoError(t, err)
+	assert.Equal(t, []docker.HostMount{
+		{Type: "bind", Source: `C:\data`, Target: "/data", ReadOnly: true, BindOptions: &docker.BindOptions{Propagation: "rshared"}},
+		{Type: "volume", Source: "db", Target: "/var/lib/postgresql/data", VolumeOptions: &docker.VolumeOptions{
+			NoCopy:       true,
+			DriverConfig: docker.VolumeDriverConfig{Name: "local", Options: map[string]string{"type": "nfs"}},
+		}},
+		{Type: "volume", Target: "/cache"},
+		{Type: "tmpfs", Target: "/run", TempfsOptions: &docker.TempfsOptions{SizeBytes: 64 << 20, Mode: 0700}},
+	}, mounts)
+}
+
+func TestHostMountsErrors(t *testing.T) {
+	tests := map[string]Mount{
+		`Invalid bind mount /data : Source "data" of a bind mount must be an absolute path of the host`:                       {Type: MountBind, Source: "data", Target: "/data"},
+		`Invalid volume mount data : Target "data" must be an absolute path`:                                                  {Type: MountVolume, Target: "data"},
+		"Invalid volume mount /data : Propagation is only allowed for bind mounts, not volume":                                {Type: MountVolume, Target: "/data", Propagation: "shared"},
+		`Invalid bind mount /data : Unknown propagation "up". Format : one of [private rprivate shared rshared slave rslave]`: {Type: MountBind, Source: "/data", Target: "/data", Propagation: "up"},
+		`Invalid bind mount /data : SELinux label "Z" can't be set on a mount, use Binds instead (ex : /srv:/data:rw,Z)`:      {Type: MountBind, Source: "/srv", Target: "/data", Propagation: "Z"},
+		"Invalid tmpfs mount /run : Volume options are only allowed for volume mounts, not tmpfs":                             {Type: MountTmpfs, Target: "/run", NoCopy: true},
+		"Invalid bind mount /run : Tmpfs size and mode are only allowed for tmpfs mounts, not bind":                           {Type: MountBind, Source: "/run", Target: "/run", TmpfsSize: 10},
+		`Invalid tmpfs mount /run : Tmpfs mounts have no source, got "run"`:                                                   {Type: MountTmpfs, Source: "run", Target: "/run"},
+		`Invalid nfs mount /data : Unknown mount type "nfs". Format : bind, volume or tmpfs`:                                  {Type: "nfs", Target: "/data"},
+	}
+	for expected, m := range tests {
+		_, err := ContainerOptions{Mounts: []Mount{m}}.hostMounts()
+		assert.EqualError(t, err, expected)
+	}
+
+	_, err := ContainerOptions{Binds: []string{"/srv:/data"}, Mounts: []Mount{{Type: MountVolume, Target: "/data"}}}.hostMounts()
+	assert.EqualError(t, err, "Invalid volume mount /data : target already mounted")
+}
+
+func TestSplitBind(t *testing.T) {
+	assert.Equal(t, []string{"/srv", "/data"}, splitBind("/srv:/data"))
+	assert.Equal(t, []string{`C:\srv`, "/data", "ro"}, splitBind(`C:\srv:/data:ro`))
+	assert.Equal(t, []string{`C:\srv`, `D:\data`}, splitBind(`C:\srv:D:\data`))
+}
@@ -0,0 +1,330 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// NetworkOptions defines options for network creation
+type NetworkOptions struct {
+	Name       string              // Name of the network
+	Driver     string              // Driver of the network (ex : bridge, overlay). bridge if empty
+	Subnet     string              // Subnet of the network. Format : CIDR (ex : 172.28.0.0/16). Chosen by the engine if empty
+	Gateway    string              // Gateway of the subnet. Chosen by the engine if empty
+	IPRange    string              // Range of the subnet containers get their IP from. Format : CIDR
+	IPAM       *docker.IPAMOptions // Full IP configuration, instead of Subnet, Gateway and IPRange
+	Internal   bool                // Restricts external access to the network
+	Attachable bool                // Allows containers to attach to a swarm network
+	EnableIPv6 bool                // Enables IPv6 on the network
+	Labels     map[string]string   // Labels of the network
+	Options    map[string]string   // Options of the driver (ex : com.docker.network.bridge.name)
+}
+
+// createOptions validates the options and converts them to the engine format
+func (o NetworkOptions) createOptions() (docker.CreateNetworkOptions, error) {
+	opts := docker.CreateNetworkOptions{
+		Name:           o.Name,
+		Driver:         o.Driver,
+		IPAM:           o.IPAM,
+		Internal:       o.Internal,
+		Attachable:     o.Attachable,
+		EnableIPv6:     o.EnableIPv6,
+		Labels:         o.Labels,
+		CheckDuplicate: true,
+	}
+	if o.Name == "" {
+		return opts, errors.New("Name is required")
+	}
+	if opts.Driver == "" {
+		opts.Driver = "bridge"
+	}
+	if len(o.Options) > 0 {
+		opts.Options = map[string]interface{}{}
+		for key, value := range o.Options {
+			opts.Options[key] = value
+		}
+	}
+
+	if o.Subnet == "" {
+		if o.Gateway != "" || o.IPRange != "" {
+			return opts, errors.New("Gateway and IP range require a subnet")
+		}
+		return opts, nil
+	}
+	if o.IPAM != nil {
+		return opts, errors.New("Subnet can't be used with IPAM, add it to the IPAM configuration instead")
+	}
+	_, subnet, err := net.ParseCIDR(o.Subnet)
+	if err != nil {
+		return opts, fmt.Errorf("Invalid subnet %q. Format : CIDR (ex : 172.28.0.0/16)", o.Subnet)
+	}
+	if o.Gateway != "" {
+		gateway := net.ParseIP(o.Gateway)
+		if gateway == nil || !subnet.Contains(gateway) {
+			return opts, fmt.Errorf("Invalid gateway %q. Format : IP address of the subnet %v", o.Gateway, o.Subnet)
+		}
+	}
+	if o.IPRange != "" {
+		ip, _, err := net.ParseCIDR(o.IPRange)
+		if err != nil || !subnet.Contains(ip) {
+			return opts, fmt.Errorf("Invalid IP range %q. Format : CIDR inside the subnet %v", o.IPRange, o.Subnet)
+		}
+	}
+	opts.IPAM = &docker.IPAMOptions{
+		Driver: "default",
+		Config: []docker.IPAMConfig{{Subnet: o.Subnet, Gateway: o.Gateway, IPRange: o.IPRange}},
+	}
+	return opts, nil
+}
+
+// CreateNetwork creates a network
+func (c *Client) CreateNetwork(o NetworkOptions) (*docker.Network, error) {
+	return c.CreateNetworkWithContext(context.Background(), o)
+}
+
+// CreateNetworkWithContext creates a network
+// The creation is aborted when the context is done
+func (c *Client) CreateNetworkWithContext(ctx context.Context, o NetworkOptions) (*docker.Network, error) {
+	opts, err := o.createOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts.Context = ctx
+	network, err := c.Docker.CreateNetwork(opts)
+	if err != nil {
+		return nil, fmt.Errorf("Can't create network %v because %w", o.Name, err)
+	}
+	return c.InspectNetworkWithContext(ctx, network.ID)
+}
+
+// InspectNetwork inspects a network from its name or id
+func (c *Client) InspectNetwork(id string) (*docker.Network, error) {
+	return c.InspectNetworkWithContext(context.Background(), id)
+}
+
+// InspectNetworkWithContext inspects a network from its name or id
+// The inspection is aborted when the context is done
+func (c *Client) InspectNetworkWithContext(ctx context.Context, id string) (*docker.Network, error) {
+	resp, err := c.request(ctx, http.MethodGet, "/networks/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		var e *docker.Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return nil, &docker.NoSuchNetwork{ID: id}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var network docker.Network
+	if err := json.NewDecoder(resp.Body).Decode(&network); err != nil {
+		return nil, fmt.Errorf("Can't decode network %v : %v", id, err)
+	}
+	return &network, nil
+}
+
+// NetworkFilters selects networks. Empty fields select everything
+type NetworkFilters struct {
+	Names  []string // Names of the networks, or parts of them
+	IDs    []string // IDs of the networks, or prefixes of them
+	Driver string   // Driver of the networks
+	Labels []string // Labels of the networks. Format : key or key=value
+	Scope  string   // Scope of the networks : local, global or swarm
+	Custom bool     // Selects only networks created by users, not the predefined ones (bridge, host, none)
+	Unused bool     // Selects only networks without containers
+}
+
+// query returns the filters in the format of the engine API
+func (f NetworkFilters) query() string {
+	filters := map[string][]string{}
+	if len(f.Names) > 0 {
+		filters["name"] = f.Names
+	}
+	if len(f.IDs) > 0 {
+		filters["id"] = f.IDs
+	}
+	if f.Driver != "" {
+		filters["driver"] = []string{f.Driver}
+	}
+	if len(f.Labels) > 0 {
+		filters["label"] = f.Labels
+	}
+	if f.Scope != "" {
+		filters["scope"] = []string{f.Scope}
+	}
+	if f.Custom {
+		filters["type"] = []string{"custom"}
+	}
+	if f.Unused {
+		filters["dangling"] = []string{"true"}
+	}
+	res, _ := json.Marshal(filters)
+	return string(res)
+}
+
+// ListNetworks lists the networks selected by the filters
+func (c *Client) ListNetworks(filters NetworkFilters) ([]docker.Network, error) {
+	return c.ListNetworksWithContext(context.Background(), filters)
+}
+
+// ListNetworksWithContext lists the networks selected by the filters
+// The listing is aborted when the context is done
+func (c *Client) ListNetworksWithContext(ctx context.Context, filters NetworkFilters) ([]docker.Network, error) {
+	query := url.Values{}
+	query.Set("filters", filters.query())
+	resp, err := c.request(ctx, http.MethodGet, "/networks", query, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Can't list networks because %w", err)
+	}
+	defer resp.Body.Close()
+
+	networks := []docker.Network{}
+	if err := json.NewDecoder(resp.Body).Decode(&networks); err != nil {
+		return nil, fmt.Errorf("Can't decode networks : %v", err)
+	}
+	return networks, nil
+}
+
+// RemoveNetwork removes a network from its name or id. Containers must be disconnected first
+func (c *Client) RemoveNetwork(id string) error {
+	return c.RemoveNetworkWithContext(context.Background(), id)
+}
+
+// RemoveNetworkWithContext removes a network from its name or id. Containers must be disconnected first
+// The removal is aborted when the context is done
+func (c *Client) RemoveNetworkWithContext(ctx context.Context, id string) error {
+	resp, err := c.request(ctx, http.MethodDelete, "/networks/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		var e *docker.Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return &docker.NoSuchNetwork{ID: id}
+		}
+		return fmt.Errorf("Can't remove network %v because %w", id, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// PruneNetworks removes the networks without containers, having the labels if any. Format : key or key=value
+func (c *Client) PruneNetworks(labels ...string) ([]string, error) {
+	return c.PruneNetworksWithContext(context.Background(), labels...)
+}
+
+// PruneNetworksWithContext removes the networks without containers, having the labels if any. Format : key or key=value
+// Returns the names of the removed networks
+// The pruning is aborted when the context is done
+func (c *Client) PruneNetworksWithContext(ctx context.Context, labels ...string) ([]string, error) {
+	filters := map[string][]string{}
+	if len(labels) > 0 {
+		filters["label"] = labels
+	}
+	res, err := c.Docker.PruneNetworks(docker.PruneNetworksOptions{Filters: filters, Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("Can't prune networks because %w", err)
+	}
+	return res.NetworksDeleted, nil
+}
+
+// EndpointOptions defines how a container is connected to a network
+type EndpointOptions struct {
+	Aliases     []string          // Names of the container on the network, in addition to its name
+	IPv4Address string            // Static IPv4 address of the container. Chosen by the engine if empty
+	IPv6Address string            // Static IPv6 address of the container. Chosen by the engine if empty
+	Links       []string          // Links to other containers of the network. Format : name:alias
+	DriverOpts  map[string]string // Options of the network driver for the endpoint
+}
+
+// endpointConfig validates the options and converts them to the engine format
+func (o EndpointOptions) endpointConfig() (*docker.EndpointConfig, error) {
+	config := &docker.EndpointConfig{Aliases: o.Aliases, Links: o.Links, DriverOpts: o.DriverOpts}
+	if o.IPv4Address != "" {
+		if ip := net.ParseIP(o.IPv4Address); ip == nil || ip.To4() == nil {
+			return nil, fmt.Errorf("Invalid IPv4 address %q", o.IPv4Address)
+		}
+	}
+	if o.IPv6Address != "" {
+		if ip := net.ParseIP(o.IPv6Address); ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("Invalid IPv6 address %q", o.IPv6Address)
+		}
+	}
+	if o.IPv4Address != "" || o.IPv6Address != "" {
+		config.IPAMConfig = &docker.EndpointIPAMConfig{IPv4Address: o.IPv4Address, IPv6Address: o.IPv6Address}
+	}
+	return config, nil
+}
+
+// ConnectNetwork connects the container to a network, from its name or id
+func (c *Container) ConnectNetwork(network string, opts EndpointOptions) error {
+	return c.ConnectNetworkWithContext(context.Background(), network, opts)
+}
+
+// ConnectNetworkWithContext connects the container to a network, from its name or id
+// A static IP requires a network created with a subnet
+func (c *Container) ConnectNetworkWithContext(ctx context.Context, network string, opts EndpointOptions) error {
+	config, err := opts.endpointConfig()
+	if err != nil {
+		return err
+	}
+	err = c.Client.Docker.ConnectNetwork(network, docker.NetworkConnectionOptions{
+		Container:      c.ID(),
+		EndpointConfig: config,
+		Context:        ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("Can't connect container %v to network %v because %w", c.Name(), network, err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// DisconnectNetwork disconnects the container from a network, from its name or id
+func (c *Container) DisconnectNetwork(network string, force bool) error {
+	return c.DisconnectNetworkWithContext(context.Background(), network, force)
+}
+
+// DisconnectNetworkWithContext disconnects the container from a network, from its name or id
+// Force disconnects the container even if it is not running
+func (c *Container) DisconnectNetworkWithContext(ctx context.Context, network string, force bool) error {
+	err := c.Client.Docker.DisconnectNetwork(network, docker.NetworkConnectionOptions{
+		Container: c.ID(),
+		Force:     force,
+		Context:   ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("Can't disconnect container %v from network %v because %w", c.Name(), network, err)
+	}
+	c.RefreshWithContext(ctx)
+	return nil
+}
+
+// NetworkAttachment is a network a container is attached to at creation
+type NetworkAttachment struct {
+	Network string // Name or id of the network
+	EndpointOptions
+}
+
+// validateAttachments checks that the networks are attachable, once each, with valid endpoint options
+func validateAttachments(attachments []NetworkAttachment) error {
+	seen := map[string]bool{}
+	for _, a := range attachments {
+		switch {
+		case a.Network == "":
+			return errors.New("Network of attachment is required")
+		case a.Network == "host" || a.Network == "none":
+			return fmt.Errorf("Network %v can't be attached, use NetworkMode instead", a.Network)
+		case seen[a.Network]:
+			return fmt.Errorf("Network %v is attached twice", a.Network)
+		}
+		seen[a.Network] = true
+		if _, err := a.endpointConfig(); err != nil {
+			return fmt.Errorf("Invalid attachment to network %v : %v", a.Network, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package dockerapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkCreateOptions(t *testing.T) {
+	opts, err := NetworkOptions{
+		Name:     "tenant-a",
+		Subnet:   "172.28.0.0/16",
+		Gateway:  "172.28.0.1",
+		IPRange:  "172.28.5.0/24",
+		Internal: true,
+		Options:  map[string]string{"com.docker.network.bridge.name": "br-tenant-a"},
+	}.createOptions()
+	assert.NoError(t, err)
+	assert.Equal(t, "bridge", opts.Driver)
+	assert.True(t, opts.Internal)
+	assert.Equal(t, &docker.IPAMOptions{
+		Driver: "default",
+		Config: []docker.IPAMConfig{{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1", IPRange: "172.28.5.0/24"}},
+	}, opts.IPAM)
+	assert.Equal(t, map[string]interface{}{"com.docker.network.bridge.name": "br-tenant-a"}, opts.Options)
+
+	tests := map[string]NetworkOptions{
+		"Name is required": {},
+		`Invalid subnet "172.28.0.0". Format : CIDR (ex : 172.28.0.0/16)`:               {Name: "n", Subnet: "172.28.0.0"},
+		`Invalid gateway "10.0.0.1". Format : IP address of the subnet 172.28.0.0/16`:   {Name: "n", Subnet: "172.28.0.0/16", Gateway: "10.0.0.1"},
+		"Gateway and IP range require a subnet":                                         {Name: "n", Gateway: "172.28.0.1"},
+		"Subnet can't be used with IPAM, add it to the IPAM configuration instead":      {Name: "n", Subnet: "172.28.0.0/16", IPAM: &docker.IPAMOptions{}},
+		`Invalid IP range "10.0.0.0/24". Format : CIDR inside the subnet 172.28.0.0/16`: {Name: "n", Subnet: "172.28.0.0/16", IPRange: "10.0.0.0/24"},
+	}
+	for expected, o := range tests {
+		_, err := o.createOptions()
+		assert.EqualError(t, err, expected)
+	}
+}
+
+func TestNetworkFiltersQuery(t *testing.T) {
+	assert.Equal(t, "{}", NetworkFilters{}.query())
+	assert.Equal(t, `{"dangling":["true"],"driver":["bridge"],"label":["env=prod"],"type":["custom"]}`,
+		NetworkFilters{Driver: "bridge", Labels: []string{"env=prod"}, Custom: true, Unused: true}.query())
+}
+
+func TestEndpointConfig(t *testing.T) {
+	config, err := EndpointOptions{Aliases: []string{"db"}, IPv4Address: "172.28.0.10"}.endpointConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, &docker.EndpointConfig{Aliases: []string{"db"}, IPAMConfig: &docker.EndpointIPAMConfig{IPv4Address: "172.28.0.10"}}, config)
+
+	_, err = EndpointOptions{IPv4Address: "fd00::1"}.endpointConfig()
+	assert.EqualError(t, err, `Invalid IPv4 address "fd00::1"`)
+	_, err = EndpointOptions{IPv6Address: "10.0.0.1"}.endpointConfig()
+	assert.EqualError(t, err, `Invalid IPv6 address "10.0.0.1"`)
+}
+
+func TestNetworkRequests(t *testing.T) {
+	var filters string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/networks":
+			filters = r.URL.Query().Get("filters")
+			w.Write([]byte(`[{"Name":"tenant-a","Id":"aaa","Driver":"bridge","Labels":{"tenant":"a"}},{"Name":"tenant-b","Id":"bbb","Internal":true}]`))
+		case r.URL.Path == "/networks/tenant-a":
+			w.Write([]byte(`{"Name":"tenant-a","Id":"aaa","Driver":"bridge"}`))
+		case r.URL.Path == "/networks/missing":
+			http.Error(w, `{"message":"network missing not found"}`, http.StatusNotFound)
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+
+	networks, err := client.ListNetworks(NetworkFilters{Labels: []string{"tenant"}, Custom: true})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"label":["tenant"],"type":["custom"]}`, filters)
+	assert.Equal(t, []docker.Network{
+		{Name: "tenant-a", ID: "aaa", Driver: "bridge", Labels: map[string]string{"tenant": "a"}},
+		{Name: "tenant-b", ID: "bbb", Internal: true},
+	}, networks)
+
+	network, err := client.InspectNetwork("tenant-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "aaa", network.ID)
+
+	_, err = client.InspectNetwork("missing")
+	assert.Equal(t, &docker.NoSuchNetwork{ID: "missing"}, err)
+	err = client.RemoveNetwork("missing")
+	assert.Equal(t, &docker.NoSuchNetwork{ID: "missing"}, err)
+	assert.NoError(t, client.RemoveNetwork("tenant-a"))
+}
+
+func TestConnectNetwork(t *testing.T) {
+	connections := map[string]docker.NetworkConnectionOptions{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && (r.URL.Path == "/networks/front/connect" || r.URL.Path == "/networks/front/disconnect"):
+			var opts docker.NetworkConnectionOptions
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&opts))
+			connections[r.URL.Path] = opts
+		case r.Method == http.MethodPost && r.URL.Path == "/networks/missing/connect":
+			http.Error(w, `{"message":"network missing not found"}`, http.StatusNotFound)
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/0123456789ab/json":
+			w.Write([]byte(`{"Id":"0123456789ab","Name":"/web","NetworkSettings":{"Networks":{"front":{"Aliases":["www"],"IPAddress":"172.28.0.10"}}}}`))
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	c := &Container{Container: &docker.Container{ID: "0123456789ab", Name: "/web"}, Client: client}
+
+	assert.NoError(t, c.ConnectNetwork("front", EndpointOptions{Aliases: []string{"www"}, IPv4Address: "172.28.0.10"}))
+	assert.Equal(t, docker.NetworkConnectionOptions{
+		Container: "0123456789ab",
+		EndpointConfig: &docker.EndpointConfig{
+			Aliases:    []string{"www"},
+			IPAMConfig: &docker.EndpointIPAMConfig{IPv4Address: "172.28.0.10"},
+		},
+	}, connections["/networks/front/connect"])
+	assert.Equal(t, "172.28.0.10", c.Container.NetworkSettings.Networks["front"].IPAddress, "container is refreshed")
+
+	assert.NoError(t, c.DisconnectNetwork("front", true))
+	assert.Equal(t, docker.NetworkConnectionOptions{Container: "0123456789ab", Force: true}, connections["/networks/front/disconnect"])
+
+	err := c.ConnectNetwork("missing", EndpointOptions{})
+	var noSuch *docker.NoSuchNetworkOrContainer
+	assert.True(t, errors.As(err, &noSuch))
+	assert.EqualError(t, err, "Can't connect container web to network missing because "+noSuch.Error())
+	assert.EqualError(t, c.ConnectNetwork("front", EndpointOptions{IPv4Address: "300.0.0.1"}), `Invalid IPv4 address "300.0.0.1"`)
+}
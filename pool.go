@@ -0,0 +1,267 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PoolOutcome is the outcome of a bulk operation for a container of a pool
+type PoolOutcome string
+
+// Outcomes of bulk operations
+const (
+	PoolSucceeded PoolOutcome = "succeeded"
+	PoolFailed    PoolOutcome = "failed"
+)
+
+// PoolContainerResult is the result of a bulk operation for a container of a pool
+type PoolContainerResult struct {
+	Outcome PoolOutcome
+	Err     error // Error of the container, if failed
+}
+
+// PoolResult is the result of a bulk operation on a pool, by container name
+// It is returned as error by bulk operations when at least one container failed :
+// use errors.As to get the result and errors.Is/errors.As to check the errors of the containers
+type PoolResult map[string]PoolContainerResult
+
+// Failed returns the names of the containers which failed, sorted
+func (r PoolResult) Failed() []string {
+	names := []string{}
+	for name, res := range r {
+		if res.Outcome == PoolFailed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Errors returns the errors of the failed containers, sorted by container name
+func (r PoolResult) Errors() []error {
+	errs := []error{}
+	for _, name := range r.Failed() {
+		errs = append(errs, r[name].Err)
+	}
+	return errs
+}
+
+// Err returns the result as error if at least one container failed, nil otherwise
+func (r PoolResult) Err() error {
+	if len(r.Failed()) == 0 {
+		return nil
+	}
+	return r
+}
+
+// Error joins the errors of the failed containers
+func (r PoolResult) Error() string {
+	failed := r.Failed()
+	messages := make([]string, 0, len(failed))
+	for _, name := range failed {
+		messages = append(messages, fmt.Sprintf("%v: %v", name, r[name].Err))
+	}
+	return fmt.Sprintf("%d of %d containers failed : %v", len(failed), len(r), strings.Join(messages, "; "))
+}
+
+// Is checks whether the error of one of the failed containers matches the target
+func (r PoolResult) Is(target error) bool {
+	for _, err := range r.Errors() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error of the failed containers matching the target, and sets the target to it
+func (r PoolResult) As(target interface{}) bool {
+	for _, err := range r.Errors() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// PoolOptions limits the load put on the engines by bulk operations
+type PoolOptions struct {
+	Workers     int           // Maximum number of containers handled at the same time, no limit if 0
+	Interval    time.Duration // Minimum delay between the start of two container operations, no limit if 0
+	PullWorkers int           // Maximum number of images pulled at the same time, no limit if 0
+	WaitHealthy bool          // Waits for containers to be healthy before starting the containers depending on them
+}
+
+// each runs the action concurrently on all containers from the pool
+// Returns the outcome of each container, by name. All errors are logged
+func (pool PoolContainer) each(action func(c *Container) error) PoolResult {
+	return pool.eachWithOptions(context.Background(), PoolOptions{}, action)
+}
+
+// eachWithOptions runs the action concurrently on all containers from the pool, within the limits of the options
+// Containers not yet handled when the context is done fail with the error of the context
+func (pool PoolContainer) eachWithOptions(ctx context.Context, opts PoolOptions, action func(c *Container) error) PoolResult {
+	type done struct {
+		name string
+		err  error
+	}
+
+	workers := newSemaphore(opts.Workers)
+	limiter := &rateLimiter{interval: opts.Interval}
+	sem := make(chan done, len(pool))
+	for _, v := range pool {
+		go func(v *Container) {
+			err := workers.acquire(ctx)
+			if err == nil {
+				defer workers.release()
+				err = limiter.wait(ctx)
+			}
+			if err == nil {
+				err = action(v)
+			}
+			sem <- done{v.Name(), err}
+		}(v)
+	}
+	result := PoolResult{}
+	for i := 0; i < len(pool); i++ {
+		d := <-sem
+		if d.err != nil {
+			log.Println(d.err)
+			result[d.name] = PoolContainerResult{Outcome: PoolFailed, Err: d.err}
+			continue
+		}
+		result[d.name] = PoolContainerResult{Outcome: PoolSucceeded}
+	}
+	return result
+}
+
+// semaphore limits the number of concurrent operations. A nil semaphore has no limit
+type semaphore chan struct{}
+
+func newSemaphore(size int) semaphore {
+	if size <= 0 {
+		return nil
+	}
+	return make(semaphore, size)
+}
+
+// acquire waits for a free slot, until the context is done
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return ctx.Err()
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// rateLimiter spaces out operations by a minimum interval
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// wait waits for the turn of the operation, until the context is done
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// imagePuller pulls each image only once for all containers of a pool, with a limited number of concurrent pulls
+type imagePuller struct {
+	forcePull bool
+	workers   semaphore
+
+	mu    sync.Mutex
+	pulls map[pullKey]*imagePull
+}
+
+// pullKey identifies an image on an engine
+type pullKey struct {
+	client *Client
+	image  string
+}
+
+// imagePull is a pull shared by containers. err is set once done is closed
+type imagePull struct {
+	done chan struct{}
+	err  error
+}
+
+func newImagePuller(forcePull bool, workers int) *imagePuller {
+	return &imagePuller{forcePull: forcePull, workers: newSemaphore(workers), pulls: map[pullKey]*imagePull{}}
+}
+
+// pull pulls the image of the container, or waits for the pull of the same image by another container
+func (p *imagePuller) pull(ctx context.Context, c *Container) error {
+	key := pullKey{c.Client, c.Image()}
+	p.mu.Lock()
+	pull, ok := p.pulls[key]
+	if !ok {
+		pull = &imagePull{done: make(chan struct{})}
+		p.pulls[key] = pull
+	}
+	p.mu.Unlock()
+
+	if !ok {
+		pull.err = p.doPull(ctx, key)
+		close(pull.done)
+		return pull.err
+	}
+	select {
+	case <-pull.done:
+		return pull.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *imagePuller) doPull(ctx context.Context, key pullKey) error {
+	if err := p.workers.acquire(ctx); err != nil {
+		return err
+	}
+	defer p.workers.release()
+	if !p.forcePull && key.client.ImageExistsWithContext(ctx, key.image) {
+		log.Printf("Image %+v already present\n", key.image)
+		return nil
+	}
+	log.Printf("Pulling %+v image\n", key.image)
+	if err := key.client.PullImageWithContext(ctx, key.image); err != nil {
+		return fmt.Errorf("Can't pull image %v : %w", key.image, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package dockerapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPoolResult(t *testing.T) {
+	notFound := &docker.NoSuchContainer{ID: "db"}
+	result := PoolResult{
+		"web":   {Outcome: PoolFailed, Err: errors.New("port already allocated")},
+		"db":    {Outcome: PoolFailed, Err: notFound},
+		"cache": {Outcome: PoolSucceeded},
+	}
+
+	assert.Equal(t, []string{"db", "web"}, result.Failed())
+	assert.EqualError(t, result, "2 of 3 containers failed : db: No such container: db; web: port already allocated")
+
+	var err error = result.Err()
+	assert.True(t, errors.Is(err, notFound))
+	var noSuch *docker.NoSuchContainer
+	assert.True(t, errors.As(err, &noSuch))
+	assert.Equal(t, "db", noSuch.ID)
+	var pool PoolResult
+	assert.True(t, errors.As(err, &pool))
+	assert.Equal(t, PoolSucceeded, pool["cache"].Outcome)
+	assert.False(t, errors.Is(err, docker.ErrNoSuchImage))
+}
+
+func TestPoolResultWithoutFailure(t *testing.T) {
+	result := PoolResult{"web": {Outcome: PoolSucceeded}}
+	assert.NoError(t, result.Err())
+	assert.Empty(t, result.Failed())
+}
+
+func TestEachWithOptionsLimitsWorkers(t *testing.T) {
+	pool := PoolContainer{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		pool = append(pool, &Container{Container: &docker.Container{Name: "/" + name}})
+	}
+
+	var mu sync.Mutex
+	running, max := 0, 0
+	result := pool.eachWithOptions(context.Background(), PoolOptions{Workers: 2}, func(c *Container) error {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if c.Name() == "c" {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	assert.Equal(t, 2, max)
+	assert.Len(t, result, 6)
+	assert.Equal(t, []string{"c"}, result.Failed())
+}
+
+func TestEachWithOptionsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pool := PoolContainer{&Container{Container: &docker.Container{Name: "/a"}}}
+	result := pool.eachWithOptions(ctx, PoolOptions{Workers: 1}, func(c *Container) error {
+		return nil
+	})
+	assert.True(t, errors.Is(result.Err(), context.Canceled))
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := &rateLimiter{interval: 20 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, limiter.wait(context.Background()))
+	}
+	assert.True(t, time.Since(start) >= 60*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, limiter.wait(ctx))
+}
@@ -0,0 +1,138 @@
+package dockerapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Layer statuses sent by the engine while pulling an image
+const (
+	PullStatusPullingFsLayer   = "Pulling fs layer"
+	PullStatusWaiting          = "Waiting"
+	PullStatusDownloading      = "Downloading"
+	PullStatusVerifying        = "Verifying Checksum"
+	PullStatusDownloadComplete = "Download complete"
+	PullStatusExtracting       = "Extracting"
+	PullStatusPullComplete     = "Pull complete"
+	PullStatusAlreadyExists    = "Already exists"
+)
+
+// PullProgress is a progress event of an image pull
+type PullProgress struct {
+	Layer   string  // ID of the layer. Empty for messages about the whole image (ex : "Digest: sha256:...")
+	Status  string  // Status of the layer (ex : Downloading, Extracting, Pull complete)
+	Current int64   // Bytes already downloaded or extracted for the current status
+	Total   int64   // Total bytes to download or extract for the current status, 0 if unknown
+	Percent float64 // Overall progress of the pull, from 0 to 100
+}
+
+// pullMessage is a JSON message of the stream sent by the engine while pulling an image
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// pullTracker aggregates layer progress into an overall progress
+type pullTracker struct {
+	layers []string           // layer ids, in order of appearance
+	done   map[string]float64 // completion of each layer, from 0 to 1
+}
+
+func newPullTracker() *pullTracker {
+	return &pullTracker{done: map[string]float64{}}
+}
+
+// update records a message of the stream and returns the matching progress event
+// Downloading a layer counts for the first half of its completion, extracting it for the second half
+func (t *pullTracker) update(msg pullMessage) PullProgress {
+	progress := PullProgress{
+		Layer:   msg.ID,
+		Status:  msg.Status,
+		Current: msg.ProgressDetail.Current,
+		Total:   msg.ProgressDetail.Total,
+	}
+	// Only layer statuses are tracked, image level messages also carry an id (the tag) but no layer status
+	if completion, ok := layerCompletion(progress); ok {
+		if _, known := t.done[msg.ID]; !known {
+			t.layers = append(t.layers, msg.ID)
+		}
+		t.done[msg.ID] = completion
+	}
+	progress.Percent = t.percent()
+	return progress
+}
+
+// percent returns the overall progress of the pull, from 0 to 100
+func (t *pullTracker) percent() float64 {
+	if len(t.layers) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, layer := range t.layers {
+		sum += t.done[layer]
+	}
+	return 100 * sum / float64(len(t.layers))
+}
+
+// layerCompletion returns the completion of a layer from its status, from 0 to 1
+// Returns false if the status is not a layer status
+func layerCompletion(p PullProgress) (float64, bool) {
+	ratio := 0.0
+	if p.Total > 0 {
+		ratio = float64(p.Current) / float64(p.Total)
+		if ratio > 1 {
+			ratio = 1
+		}
+	}
+	switch p.Status {
+	case PullStatusPullingFsLayer, PullStatusWaiting:
+		return 0, true
+	case PullStatusDownloading:
+		return ratio / 2, true
+	case PullStatusVerifying, PullStatusDownloadComplete:
+		return 0.5, true
+	case PullStatusExtracting:
+		return 0.5 + ratio/2, true
+	case PullStatusPullComplete, PullStatusAlreadyExists:
+		return 1, true
+	}
+	return 0, false
+}
+
+// decodePullProgress reads the JSON stream of an image pull until its end, sending each event to progress if not nil
+// Returns the error embedded in the stream by the engine if any
+func decodePullProgress(r io.Reader, progress func(PullProgress)) error {
+	tracker := newPullTracker()
+	decoder := json.NewDecoder(r)
+	for {
+		var msg pullMessage
+		err := decoder.Decode(&msg)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Can't decode pull progress : %v", err)
+		}
+		if msg.ErrorDetail.Message != "" {
+			return errors.New(msg.ErrorDetail.Message)
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
+		}
+		event := tracker.update(msg)
+		if progress != nil && strings.TrimSpace(event.Status) != "" {
+			progress(event)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package dockerapi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodePullProgress(t *testing.T) {
+	stream := `{"status":"Pulling from library/redis","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a1"}
+{"status":"Already exists","progressDetail":{},"id":"b2"}
+{"status":"Downloading","progressDetail":{"current":50,"total":100},"progress":"[=====>    ]","id":"a1"}
+{"status":"Download complete","progressDetail":{},"id":"a1"}
+{"status":"Extracting","progressDetail":{"current":50,"total":100},"id":"a1"}
+{"status":"Pull complete","progressDetail":{},"id":"a1"}
+{"status":"Digest: sha256:abc"}
+`
+	events := []PullProgress{}
+	err := decodePullProgress(strings.NewReader(stream), func(p PullProgress) {
+		events = append(events, p)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, events, 8)
+
+	percents := []float64{}
+	for _, e := range events {
+		percents = append(percents, e.Percent)
+	}
+	assert.Equal(t, []float64{0, 0, 50, 62.5, 75, 87.5, 100, 100}, percents)
+
+	assert.Equal(t, PullProgress{Layer: "a1", Status: PullStatusDownloading, Current: 50, Total: 100, Percent: 62.5}, events[3])
+	assert.Equal(t, "", events[7].Layer)
+}
+
+func TestDecodePullProgressError(t *testing.T) {
+	stream := `{"status":"Pulling fs layer","progressDetail":{},"id":"a1"}
+{"errorDetail":{"message":"manifest for redis:nope not found"},"error":"manifest for redis:nope not found"}
+{"status":"Pull complete","progressDetail":{},"id":"a1"}
+`
+	count := 0
+	err := decodePullProgress(strings.NewReader(stream), func(p PullProgress) {
+		count++
+	})
+	assert.EqualError(t, err, "manifest for redis:nope not found")
+	assert.Equal(t, 1, count)
+}
+
+func TestDecodePullProgressWithoutCallback(t *testing.T) {
+	err := decodePullProgress(strings.NewReader(`{"error":"unauthorized"}`), nil)
+	assert.EqualError(t, err, "unauthorized")
+	err = decodePullProgress(strings.NewReader(`{"status":`), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+package dockerapi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultVolumeHelperImage is the image of the helper containers reading and writing volumes. It must provide sh, tar and tail
+const defaultVolumeHelperImage = "busybox:latest"
+
+// volumeHelperPath is the path of the volume inside helper containers
+const volumeHelperPath = "/volume"
+
+// VolumeArchive describes a tar archive of a volume
+type VolumeArchive struct {
+	Size       int64  // Size of the archive in bytes, as written or read
+	Checksum   string // Checksum of the archive, as written or read. Format : sha256:hex
+	Compressed bool   // True if the archive is compressed with gzip
+}
+
+// VolumeBackupOptions defines how a volume is backed up
+type VolumeBackupOptions struct {
+	Compress bool   // Compresses the archive with gzip
+	Image    string // Image of the helper container. busybox:latest if empty
+}
+
+// VolumeRestoreOptions defines how a volume is restored
+type VolumeRestoreOptions struct {
+	Force    bool   // Replaces the content of a non-empty volume. A non-empty volume is not restored otherwise
+	Checksum string // Expected checksum of the archive, as returned by BackupVolume. Not checked if empty
+	Image    string // Image of the helper container. busybox:latest if empty
+}
+
+// BackupVolume writes a tar archive of the content of the volume, possibly compressed
+// The archive is read by a helper container mounting the volume read only, removed once done
+// Returns the checksum of the archive, to check it on restore
+func (c *Client) BackupVolume(ctx context.Context, name string, w io.Writer, opts VolumeBackupOptions) (VolumeArchive, error) {
+	archive := VolumeArchive{Compressed: opts.Compress}
+	if _, err := c.InspectVolume(ctx, name); err != nil {
+		return archive, fmt.Errorf("Can't backup volume %v because %v", name, err.Error())
+	}
+	helper, err := c.runVolumeHelper(ctx, "backup", name, opts.Image, true)
+	if err != nil {
+		return archive, err
+	}
+	defer removeVolumeHelper(helper)
+
+	checksum := newChecksumWriter(w)
+	out := io.Writer(checksum)
+	var compressor *gzip.Writer
+	if opts.Compress {
+		compressor = gzip.NewWriter(checksum)
+		out = compressor
+	}
+
+	res, err := helper.ExecWithOptions(ctx, ExecOptions{
+		Cmd:    []string{"tar", "-cf", "-", "-C", volumeHelperPath, "."},
+		Stdout: out,
+	})
+	if err == nil && res.ExitCode != 0 {
+		err = fmt.Errorf("tar exited with code %v : %v", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	if err == nil && compressor != nil {
+		err = compressor.Close()
+	}
+	if err != nil {
+		return archive, fmt.Errorf("Can't backup volume %v because %v", name, err.Error())
+	}
+	archive.Size, archive.Checksum = checksum.size, checksum.sum()
+	return archive, nil
+}
+
+// RestoreVolume extracts a tar archive, possibly compressed with gzip, into the volume. The volume is created if it does not exist
+// A non-empty volume is only restored if forced, its content being removed first
+// The archive is written by a helper container mounting the volume, removed once done
+// With a checksum, the archive is first stored in a temporary file, to check it before touching the volume
+func (c *Client) RestoreVolume(ctx context.Context, name string, r io.Reader, opts VolumeRestoreOptions) (VolumeArchive, error) {
+	archive := VolumeArchive{}
+	if opts.Checksum != "" {
+		file, err := spoolArchive(r, opts.Checksum)
+		if err != nil {
+			return archive, fmt.Errorf("Can't restore volume %v because %v", name, err.Error())
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+		r = file
+	}
+	checksum := newChecksumReader(r)
+	in, compressed, err := archiveReader(checksum)
+	if err != nil {
+		return archive, fmt.Errorf("Can't restore volume %v because %v", name, err.Error())
+	}
+	archive.Compressed = compressed
+
+	helper, err := c.runVolumeHelper(ctx, "restore", name, opts.Image, false)
+	if err != nil {
+		return archive, err
+	}
+	defer removeVolumeHelper(helper)
+
+	res, err := helper.ExecWithOptions(ctx, ExecOptions{Cmd: []string{"ls", "-A", volumeHelperPath}})
+	if err != nil {
+		return archive, fmt.Errorf("Can't restore volume %v because %v", name, err.Error())
+	}
+	if strings.TrimSpace(res.Stdout) != "" {
+		if !opts.Force {
+			return archive, fmt.Errorf("Can't restore volume %v because it is not empty", name)
+		}
+		script := fmt.Sprintf("rm -rf %[1]v/..?* %[1]v/.[!.]* %[1]v/*", volumeHelperPath)
+		res, err = helper.ExecWithOptions(ctx, ExecOptions{Cmd: []string{"sh", "-c", script}})
+		if err == nil && res.ExitCode != 0 {
+			err = fmt.Errorf("content can't be removed : %v", strings.TrimSpace(res.Stderr))
+		}
+		if err != nil {
+			return archive, fmt.Errorf("Can't restore volume %v because %v", name, err.Error())
+		}
+	}
+
+	res, err = helper.ExecWithOptions(ctx, ExecOptions{
+		Cmd:   []string{"tar", "-xf", "-", "-C", volumeHelperPath},
+		Stdin: in,
+	})
+	if err == nil && res.ExitCode != 0 {
+		err = fmt.Errorf("tar exited with code %v : %v", res.ExitCode, strings.TrimSpace(res.Stderr))
+	}
+	if err == nil {
+		// The padding after the end of the archive is part of the checksum
+		_, err = io.Copy(io.Discard, checksum)
+	}
+	if err != nil {
+		return archive, fmt.Errorf("Can't restore volume %v because %v", name, err.Error())
+	}
+	archive.Size, archive.Checksum = checksum.size, checksum.sum()
+	return archive, nil
+}
+
+// runVolumeHelper runs a helper container mounting the volume, idle until removed
+func (c *Client) runVolumeHelper(ctx context.Context, action, volume, image string, readOnly bool) (*Container, error) {
+	if image == "" {
+		image = defaultVolumeHelperImage
+	}
+	helper, err := c.NewContainer(ContainerOptions{
+		Image:       image,
+		Name:        fmt.Sprintf("dockerapi-%v-%v-%d", action, volume, time.Now().UnixNano()),
+		Cmd:         []string{"tail", "-f", "/dev/null"},
+		NetworkMode: "none",
+		Labels:      map[string]string{"dockerapi.volume-helper": volume},
+		Mounts:      []Mount{{Type: MountVolume, Source: volume, Target: volumeHelperPath, ReadOnly: readOnly}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := helper.RunWithContext(ctx, false); err != nil {
+		if helper.ID() != "" {
+			removeVolumeHelper(helper)
+		}
+		return nil, fmt.Errorf("Can't run helper container for volume %v because %v", volume, err.Error())
+	}
+	return helper, nil
+}
+
+// removeVolumeHelper removes a helper container, even when the context of the operation is done
+func removeVolumeHelper(helper *Container) {
+	if err := helper.RemoveWithContext(context.Background(), false); err != nil {
+		log.Println(err)
+	}
+}
+
+// archiveReader returns a reader of the tar archive, decompressing it if it is compressed with gzip
+func archiveReader(r io.Reader) (io.Reader, bool, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, false, err
+	}
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return buffered, false, nil
+	}
+	decompressed, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, true, err
+	}
+	return decompressed, true, nil
+}
+
+// spoolArchive stores the archive in a temporary file, checking its checksum
+// Returns the file, positioned at its beginning
+func spoolArchive(r io.Reader, expected string) (*os.File, error) {
+	file, err := os.CreateTemp("", "dockerapi-volume-*.tar")
+	if err != nil {
+		return nil, err
+	}
+	checksum := newChecksumWriter(file)
+	_, err = io.Copy(checksum, r)
+	if err == nil && checksum.sum() != expected {
+		err = fmt.Errorf("checksum of the archive is %v, expected %v", checksum.sum(), expected)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// checksumWriter computes the checksum and size of the data written to a writer
+type checksumWriter struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func newChecksumWriter(w io.Writer) *checksumWriter {
+	return &checksumWriter{w: w, hash: sha256.New()}
+}
+
+func (c *checksumWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *checksumWriter) sum() string {
+	return "sha256:" + hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// checksumReader computes the checksum and size of the data read from a reader
+type checksumReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+}
+
+func newChecksumReader(r io.Reader) *checksumReader {
+	return &checksumReader{r: r, hash: sha256.New()}
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+func (c *checksumReader) sum() string {
+	return "sha256:" + hex.EncodeToString(c.hash.Sum(nil))
+}
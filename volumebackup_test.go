@@ -0,0 +1,186 @@
+package dockerapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveReader(t *testing.T) {
+	r, compressed, err := archiveReader(strings.NewReader("plain tar"))
+	assert.NoError(t, err)
+	assert.False(t, compressed)
+	content, _ := io.ReadAll(r)
+	assert.Equal(t, "plain tar", string(content))
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte("compressed tar"))
+	gz.Close()
+	r, compressed, err = archiveReader(&buf)
+	assert.NoError(t, err)
+	assert.True(t, compressed)
+	content, _ = io.ReadAll(r)
+	assert.Equal(t, "compressed tar", string(content))
+
+	_, compressed, err = archiveReader(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.False(t, compressed)
+}
+
+func TestChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	w := newChecksumWriter(&buf)
+	w.Write([]byte("hello"))
+	assert.Equal(t, int64(5), w.size)
+	assert.Equal(t, "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", w.sum())
+
+	r := newChecksumReader(&buf)
+	io.Copy(io.Discard, r)
+	assert.Equal(t, w.sum(), r.sum())
+}
+
+func TestSpoolArchive(t *testing.T) {
+	file, err := spoolArchive(strings.NewReader("hello"), "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824")
+	assert.NoError(t, err)
+	content, _ := io.ReadAll(file)
+	assert.Equal(t, "hello", string(content))
+	file.Close()
+	os.Remove(file.Name())
+
+	_, err = spoolArchive(strings.NewReader("hello"), "sha256:00")
+	assert.EqualError(t, err, "checksum of the archive is sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824, expected sha256:00")
+}
+
+// fakeVolumeEngine answers the requests of the helper containers of a volume, whose content is kept as an archive
+// tar, ls and sh are run by the exec requests. The commands and the mounts of the helpers are recorded
+type fakeVolumeEngine struct {
+	archive []byte
+	mounts  []docker.HostMount
+	cmds    [][]string
+	removed int
+}
+
+func (e *fakeVolumeEngine) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/version":
+			w.Write([]byte(`{"ApiVersion":"1.41"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/volumes/data":
+			w.Write([]byte(`{"Name":"data","Driver":"local"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/images/busybox:latest/json":
+			w.Write([]byte(`{"Id":"sha256:busybox"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/create":
+			var body struct{ HostConfig docker.HostConfig }
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.True(t, strings.HasPrefix(r.URL.Query().Get("name"), "dockerapi-"))
+			e.mounts = append(e.mounts, body.HostConfig.Mounts...)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id":"helper"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/helper/start":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/helper/json":
+			w.Write([]byte(`{"Id":"helper","Name":"/dockerapi-helper","State":{"Running":true}}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/containers/helper":
+			e.removed++
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/containers/helper/exec":
+			var opts docker.CreateExecOptions
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&opts))
+			e.cmds = append(e.cmds, opts.Cmd)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"Id":"exec%d"}`, len(e.cmds)-1)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/start"):
+			io.Copy(io.Discard, r.Body)
+			var i int
+			fmt.Sscanf(r.URL.Path, "/exec/exec%d/start", &i)
+			conn, buf := hijackExec(t, w)
+			if conn == nil {
+				return
+			}
+			defer conn.Close()
+			stdin, _ := io.ReadAll(buf)
+			switch cmd := strings.Join(e.cmds[i], " "); cmd {
+			case "tar -cf - -C /volume .":
+				writeFrame(buf, 1, string(e.archive))
+			case "ls -A /volume":
+				if len(e.archive) > 0 {
+					writeFrame(buf, 1, "file\n")
+				}
+			case "sh -c rm -rf /volume/..?* /volume/.[!.]* /volume/*":
+				e.archive = nil
+			case "tar -xf - -C /volume":
+				e.archive = stdin
+			default:
+				t.Errorf("Unexpected command %v", cmd)
+			}
+			buf.Flush()
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/exec/"):
+			w.Write([]byte(`{"Running":false,"ExitCode":0}`))
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	}
+}
+
+func TestBackupAndRestoreVolume(t *testing.T) {
+	// Binary content without line ending, larger than the default maximum length of a line
+	content := bytes.Repeat([]byte{0x00, 0xff, 0x7f}, 1024*1024)
+	engine := &fakeVolumeEngine{archive: content}
+	client := newTestClient(t, engine.handle(t))
+
+	var backup bytes.Buffer
+	archive, err := client.BackupVolume(context.Background(), "data", &backup, VolumeBackupOptions{})
+	assert.NoError(t, err)
+	sum := sha256.Sum256(content)
+	assert.Equal(t, VolumeArchive{Size: int64(len(content)), Checksum: "sha256:" + hex.EncodeToString(sum[:])}, archive)
+	assert.Equal(t, content, backup.Bytes())
+	assert.Equal(t, []docker.HostMount{{Type: "volume", Source: "data", Target: "/volume", ReadOnly: true}}, engine.mounts)
+	assert.Equal(t, 1, engine.removed, "helper container is removed")
+
+	backup.Reset()
+	archive, err = client.BackupVolume(context.Background(), "data", &backup, VolumeBackupOptions{Compress: true})
+	assert.NoError(t, err)
+	sum = sha256.Sum256(backup.Bytes())
+	assert.Equal(t, VolumeArchive{Size: int64(backup.Len()), Checksum: "sha256:" + hex.EncodeToString(sum[:]), Compressed: true}, archive)
+
+	// A non-empty volume is only restored if forced
+	compressed := backup.Bytes()
+	_, err = client.RestoreVolume(context.Background(), "data", bytes.NewReader(compressed), VolumeRestoreOptions{})
+	assert.EqualError(t, err, "Can't restore volume data because it is not empty")
+	assert.Equal(t, content, engine.archive)
+	assert.Equal(t, 3, engine.removed)
+
+	engine.cmds = nil
+	restored, err := client.RestoreVolume(context.Background(), "data", bytes.NewReader(compressed), VolumeRestoreOptions{Force: true, Checksum: archive.Checksum})
+	assert.NoError(t, err)
+	assert.Equal(t, archive, restored)
+	assert.Equal(t, content, engine.archive, "archive is decompressed before being extracted")
+	assert.Equal(t, []string{"ls -A /volume", "sh -c rm -rf /volume/..?* /volume/.[!.]* /volume/*", "tar -xf - -C /volume"}, joinCmds(engine.cmds))
+	assert.Equal(t, 4, engine.removed)
+
+	// The archive is checked before touching the volume
+	_, err = client.RestoreVolume(context.Background(), "data", bytes.NewReader(compressed), VolumeRestoreOptions{Force: true, Checksum: "sha256:0"})
+	assert.ErrorContains(t, err, "checksum of the archive is "+archive.Checksum)
+	assert.Equal(t, 4, engine.removed, "no helper container is run")
+}
+
+func joinCmds(cmds [][]string) []string {
+	joined := []string{}
+	for _, cmd := range cmds {
+		joined = append(joined, strings.Join(cmd, " "))
+	}
+	return joined
+}
@@ -0,0 +1,135 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// VolumeOptions defines options for volume creation
+type VolumeOptions struct {
+	Name       string            // Name of the volume. Generated by the engine if empty
+	Driver     string            // Driver of the volume. local if empty
+	DriverOpts map[string]string // Options of the driver (ex : type=nfs, o=addr=10.0.0.1, device=:/data for local NFS volumes)
+	Labels     map[string]string // Labels of the volume
+}
+
+// CreateVolume creates a volume. Creating an existing volume returns it
+// The creation is aborted when the context is done
+func (c *Client) CreateVolume(ctx context.Context, o VolumeOptions) (*docker.Volume, error) {
+	if o.Driver == "" {
+		o.Driver = "local"
+	}
+	volume, err := c.Docker.CreateVolume(docker.CreateVolumeOptions{
+		Name:       o.Name,
+		Driver:     o.Driver,
+		DriverOpts: o.DriverOpts,
+		Labels:     o.Labels,
+		Context:    ctx,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Can't create volume %v because %v", o.Name, err.Error())
+	}
+	return volume, nil
+}
+
+// InspectVolume inspects a volume from its name
+// The inspection is aborted when the context is done
+func (c *Client) InspectVolume(ctx context.Context, name string) (*docker.Volume, error) {
+	resp, err := c.request(ctx, http.MethodGet, "/volumes/"+url.PathEscape(name), nil, nil)
+	if err != nil {
+		var e *docker.Error
+		if errors.As(err, &e) && e.Status == http.StatusNotFound {
+			return nil, docker.ErrNoSuchVolume
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var volume docker.Volume
+	if err := json.NewDecoder(resp.Body).Decode(&volume); err != nil {
+		return nil, fmt.Errorf("Can't decode volume %v : %v", name, err)
+	}
+	return &volume, nil
+}
+
+// VolumeFilters selects volumes. Empty fields select everything
+type VolumeFilters struct {
+	Names    []string // Names of the volumes, or parts of them
+	Driver   string   // Driver of the volumes
+	Labels   []string // Labels of the volumes. Format : key or key=value
+	Dangling bool     // Selects only volumes not used by any container
+}
+
+// query returns the filters in the format of the engine API
+func (f VolumeFilters) query() map[string][]string {
+	filters := map[string][]string{}
+	if len(f.Names) > 0 {
+		filters["name"] = f.Names
+	}
+	if f.Driver != "" {
+		filters["driver"] = []string{f.Driver}
+	}
+	if len(f.Labels) > 0 {
+		filters["label"] = f.Labels
+	}
+	if f.Dangling {
+		filters["dangling"] = []string{"true"}
+	}
+	return filters
+}
+
+// ListVolumes lists the volumes selected by the filters
+// The listing is aborted when the context is done
+func (c *Client) ListVolumes(ctx context.Context, filters VolumeFilters) ([]docker.Volume, error) {
+	volumes, err := c.Docker.ListVolumes(docker.ListVolumesOptions{Filters: filters.query(), Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("Can't list volumes because %v", err.Error())
+	}
+	return volumes, nil
+}
+
+// RemoveVolume removes a volume from its name. A volume used by a container can't be removed
+// Force removes the volume even if its driver fails to remove its data
+// The removal is aborted when the context is done
+func (c *Client) RemoveVolume(ctx context.Context, name string, force bool) error {
+	err := c.Docker.RemoveVolumeWithOptions(docker.RemoveVolumeOptions{Name: name, Force: force, Context: ctx})
+	if err != nil {
+		// Wrapped to check docker.ErrNoSuchVolume and docker.ErrVolumeInUse
+		return fmt.Errorf("Can't remove volume %v because %w", name, err)
+	}
+	return nil
+}
+
+// PruneVolumes removes the volumes not used by any container, having the labels if any. Format : key or key=value
+// Only anonymous volumes are removed by recent engines, unless all is true
+// Returns the names of the removed volumes and the space reclaimed in bytes
+func (c *Client) PruneVolumes(ctx context.Context, all bool, labels ...string) ([]string, int64, error) {
+	filters := map[string][]string{}
+	if len(labels) > 0 {
+		filters["label"] = labels
+	}
+	if all {
+		filters["all"] = []string{"true"}
+	}
+	res, err := c.Docker.PruneVolumes(docker.PruneVolumesOptions{Filters: filters, Context: ctx})
+	if err != nil {
+		return nil, 0, fmt.Errorf("Can't prune volumes because %v", err.Error())
+	}
+	return res.VolumesDeleted, res.SpaceReclaimed, nil
+}
+
+// VolumeUsers lists the running and non-running containers using the volume
+// A volume is safe to remove when no container uses it
+func (c *Client) VolumeUsers(ctx context.Context, name string) (SimpleContainers, error) {
+	return c.listContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"volume": {name}},
+		Context: ctx,
+	})
+}
@@ -0,0 +1,67 @@
+package dockerapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVolumeFiltersQuery(t *testing.T) {
+	assert.Empty(t, VolumeFilters{}.query())
+	assert.Equal(t, map[string][]string{
+		"dangling": {"true"},
+		"label":    {"env=prod", "backup"},
+	}, VolumeFilters{Labels: []string{"env=prod", "backup"}, Dangling: true}.query())
+}
+
+func TestVolumeRequests(t *testing.T) {
+	var listFilters, pruneFilters map[string][]string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/volumes/data":
+			w.Write([]byte(`{"Name":"data","Driver":"local","Mountpoint":"/var/lib/docker/volumes/data/_data","Labels":{"env":"prod"}}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/volumes/missing":
+			http.Error(w, `{"message":"get missing: no such volume"}`, http.StatusNotFound)
+		case r.Method == http.MethodGet && r.URL.Path == "/containers/json":
+			assert.Equal(t, "1", r.URL.Query().Get("all"))
+			assert.NoError(t, json.Unmarshal([]byte(r.URL.Query().Get("filters")), &listFilters))
+			w.Write([]byte(`[{"Id":"0123456789ab","Names":["/db"],"State":"running"},{"Id":"ba9876543210","Names":["/backup"],"State":"exited"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/volumes/prune":
+			pruneFilters = nil
+			if filters := r.URL.Query().Get("filters"); filters != "" {
+				assert.NoError(t, json.Unmarshal([]byte(filters), &pruneFilters))
+			}
+			w.Write([]byte(`{"VolumesDeleted":["old","cache"],"SpaceReclaimed":2048}`))
+		default:
+			t.Errorf("Unexpected request %v %v", r.Method, r.URL)
+		}
+	})
+	ctx := context.Background()
+
+	volume, err := client.InspectVolume(ctx, "data")
+	assert.NoError(t, err)
+	assert.Equal(t, &docker.Volume{Name: "data", Driver: "local", Mountpoint: "/var/lib/docker/volumes/data/_data", Labels: map[string]string{"env": "prod"}}, volume)
+	_, err = client.InspectVolume(ctx, "missing")
+	assert.Equal(t, docker.ErrNoSuchVolume, err)
+
+	// Stopped containers use the volume too
+	users, err := client.VolumeUsers(ctx, "data")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"volume": {"data"}}, listFilters)
+	assert.Equal(t, []string{"0123456789ab", "ba9876543210"}, users.GetIDs())
+	assert.Equal(t, "backup", users.GetAll()[1].Name())
+
+	removed, space, err := client.PruneVolumes(ctx, true, "env=test")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{"all": {"true"}, "label": {"env=test"}}, pruneFilters)
+	assert.Equal(t, []string{"old", "cache"}, removed)
+	assert.Equal(t, int64(2048), space)
+
+	_, _, err = client.PruneVolumes(ctx, false)
+	assert.NoError(t, err)
+	assert.Empty(t, pruneFilters["all"], "only anonymous volumes are pruned")
+}